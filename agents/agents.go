@@ -1,11 +1,17 @@
 package agents
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/ant-libs-go/ant-agent/llm"
 	"github.com/ant-libs-go/ant-agent/mcps"
+	"github.com/ant-libs-go/util"
 	openai "github.com/sashabaranov/go-openai"
 )
 
@@ -13,22 +19,101 @@ type Agent interface {
 	Name() string
 	Description() string
 	Clone() Agent
-	Execute(ctx *Context, task *Task) (*Result, error)
+	Execute(c context.Context, ctx *Context, task *Task) (*Result, error)
+}
+
+// ParameterizedAgent 为需要特定参数的 Agent 实现，规划结果中缺少这些参数时会被要求修正
+type ParameterizedAgent interface {
+	RequiredParameters() []string
+}
+
+// ReplanningAgent 为会动态插入任务的 Agent 实现，InsertedTasks 返回单次执行插入的任务数，
+// 执行前据此判断插入后是否会超出动态规划上限，未实现时按插入 1 个任务计算
+type ReplanningAgent interface {
+	InsertedTasks() int
 }
 
 type Context struct {
-	Input     string `json:"input"`
-	Plans     string `json:"plans"`
-	McpClient *mcps.McpClient
-	Offset    int     `json:"offset"`
-	Tasks     []*Task `json:"tasks"`
+	sync.RWMutex `json:"-"` // 保护 Tasks 等被调度器修改的字段
+
+	Id            string           `json:"id"`
+	Input         string           `json:"input"`
+	Plans         string           `json:"plans"`
+	Memory        *Memory          `json:"memory,omitempty"` // 此前各轮研究的对话记忆
+	McpClient     *mcps.McpClient  `json:"-"`
+	Events        Events           `json:"-"`
+	Offset        int              `json:"offset"`
+	Tasks         []*Task          `json:"tasks"`
+	Usage         *UsageStats      `json:"usage,omitempty"`
+	Sources       *Sources         `json:"sources,omitempty"` // 本次研究检索到的来源
+	Replans       int              `json:"replans,omitempty"` // 本次研究中动态插入任务的次数
+	Reports       []*ReportVersion `json:"reports,omitempty"` // 报告的各个修订版本
+	CurrentReport int              `json:"current_report,omitempty"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+
+	replanLimit string // 不为空时任务不能再动态插入新任务，内容为达到的上限
 }
 
 func (this *Context) ClearChatHistory() {
+	this.Id = ""
 	this.Input = ""
 	this.Plans = ""
 	this.Offset = 0
 	this.Tasks = []*Task{}
+	this.Usage = nil
+	this.Replans = 0
+	this.Memory = nil
+	this.Sources = nil
+	this.ClearReports()
+}
+
+func (this *Context) MarshalJSON() ([]byte, error) {
+	this.RLock()
+	defer this.RUnlock()
+
+	type context Context
+	return json.Marshal((*context)(this))
+}
+
+// References 返回 task 所依赖任务的输出，task.AllPrevious 或 all 为 true 时返回该任务之前所有任务的输出
+func (this *Context) References(task *Task, all bool) (r []string) {
+	all = all || task.AllPrevious
+	for i, t := range this.Tasks {
+		if len(t.Output) == 0 {
+			continue
+		}
+		if all && i >= this.Offset {
+			continue
+		}
+		if exists, _ := util.InSlice(t.Id, task.DependsOn); !all && !exists {
+			continue
+		}
+		r = append(r, fmt.Sprintf("Output from %s task:\n%s", t.Name, t.Output))
+	}
+	return
+}
+
+// fork 为并发执行的任务生成上下文快照，Offset 指向该任务，
+// 任务列表为值拷贝，避免与调度器的写入产生竞争
+func (this *Context) fork(task *Task) (r *Context) {
+	r = &Context{
+		Input:     this.Input,
+		Plans:     this.Plans,
+		Memory:    this.Memory,
+		Sources:   this.Sources,
+		McpClient: this.McpClient,
+		Events:    this.Events,
+		Usage:     NewUsageStats(),
+		Tasks:     make([]*Task, 0, len(this.Tasks)),
+	}
+	for i, t := range this.Tasks {
+		if t == task {
+			r.Offset = i
+		}
+		cp := *t
+		r.Tasks = append(r.Tasks, &cp)
+	}
+	return
 }
 
 type Result struct {
@@ -36,11 +121,46 @@ type Result struct {
 	Output string  `json:"output"`
 }
 
+type TaskStatus string
+
+const (
+	TaskStatusPending TaskStatus = ""
+	TaskStatusRunning TaskStatus = "running"
+	TaskStatusDone    TaskStatus = "done"
+	TaskStatusFailed  TaskStatus = "failed"
+)
+
 type Task struct {
+	Id          string                 `json:"id"`
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
 	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	DependsOn   []string               `json:"depends_on"`             // nil 表示依赖前一个任务，空数组表示无依赖
+	AllPrevious bool                   `json:"all_previous,omitempty"` // 引用此前所有任务的输出，而不仅是所依赖任务的输出
+	Status      TaskStatus             `json:"status,omitempty"`
 	Output      string                 `json:"output"`
+	Usage       *Usage                 `json:"usage,omitempty"`
+	Replans     int                    `json:"replans,omitempty"` // 任务通过动态规划重新执行的次数
+}
+
+// 任务是否已经结束（成功或失败），结束的任务不会再被调度
+func (this *Task) Finished() bool {
+	return this.Status == TaskStatusDone || this.Status == TaskStatusFailed
+}
+
+// IntParameter 读取整数类型的任务参数，参数可能来自 JSON 数字或字符串，缺失或无法解析时返回 def
+func (this *Task) IntParameter(name string, def int) int {
+	switch v := this.Parameters[name].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return n
+		}
+	}
+	return def
 }
 
 type CommonAgent struct {
@@ -91,6 +211,23 @@ func (this *CommonAgent) AddDeveloperMessage(content string) {
 	})
 }
 
+// chat 以流式方式请求 LLM，请求、应答与增量内容均以事件发送，echo 为 true 时增量内容标记为需要实时展示，
+// 调用的 token 用量按 agent 记录到 ctx
+func (this *CommonAgent) chat(c context.Context, ctx *Context, task *Task, agent string, provider llm.Provider, req *llm.Request, echo bool) (r *llm.Response, err error) {
+	ctx.Emit(EventLLMRequest, task, &LLMEvent{Agent: agent, Request: req})
+	r, err = provider.ChatStream(ctx.llmContext(c, task, agent), req, func(delta string) {
+		ctx.Emit(EventDelta, task, &DeltaEvent{Agent: agent, Content: delta, Echo: echo})
+	})
+	if err != nil {
+		ctx.Emit(EventLLMResponse, task, &LLMEvent{Agent: agent, Error: err.Error()})
+		return
+	}
+	ctx.Emit(EventLLMResponse, task, &LLMEvent{Agent: agent, Response: r})
+
+	ctx.addLLMUsage(agent, req, r)
+	return
+}
+
 func TrimLLMResp(inp string) string {
 	// 如果存在 ```json 前缀，则剔除
 	if idx := strings.Index(inp, "```json"); idx != -1 {
@@ -106,8 +243,3 @@ func TrimLLMResp(inp string) string {
 
 	return strings.TrimSpace(inp)
 }
-
-func LogStruct(prefix string, obj interface{}) {
-	b, _ := json.Marshal(obj)
-	fmt.Printf("%s: %s\n", prefix, string(b))
-}
@@ -6,8 +6,7 @@ import (
 	"strings"
 
 	antagent "github.com/ant-libs-go/ant-agent"
-	"github.com/ant-libs-go/util"
-	"github.com/sashabaranov/go-openai"
+	"github.com/ant-libs-go/ant-agent/llm"
 )
 
 const AnalyzeAgentSystemPrompt = `你是一个分析助手，负责综合和分析信息。请提供清晰、结构化的分析。`
@@ -15,22 +14,22 @@ const AnalyzeAgentUserPromptFormat = `用户的重要指令/请求: %s
 分析以下信息并 %s:
 %s
 
+资料中以 [n] 标注的是来源编号，引用其中的事实或数据时请保留对应的编号，例如 [3]。
 如果提供的信息不足以完成分析，你可以请求更多信息。
 如果需要更多信息，请仅回复 'MISSING_INFO: <具体的搜索查询>'。例如: 'MISSING_INFO: 2024年Q3特斯拉财报数据'`
+const AnalyzeAgentBestEffortPrompt = `已经无法再补充检索更多信息。请不要再回复 MISSING_INFO，基于现有信息给出尽可能完整的分析，并明确指出因信息不足而无法确定的部分。`
 
 type AnalyzeSubAgent struct {
 	CommonAgent
-	cfg *antagent.Config
-	cli *openai.Client
+	cfg      *antagent.Config
+	provider llm.Provider
 }
 
-func NewAnalyzeSubAgent(cfg *antagent.Config) (r *AnalyzeSubAgent) {
+func NewAnalyzeSubAgent(cfg *antagent.Config, provider llm.Provider) (r *AnalyzeSubAgent) {
 	r = &AnalyzeSubAgent{
-		cfg: cfg,
+		cfg:      cfg,
+		provider: provider,
 	}
-	openaiCfg := openai.DefaultConfig(cfg.ApiKey)
-	openaiCfg.BaseURL = cfg.ApiBase
-	r.cli = openai.NewClientWithConfig(openaiCfg)
 
 	r.AddSystemMessage(AnalyzeAgentSystemPrompt)
 	return
@@ -46,46 +45,57 @@ func (this *AnalyzeSubAgent) Description() string {
 
 func (this *AnalyzeSubAgent) Clone() Agent {
 	r := &AnalyzeSubAgent{
-		cfg: this.cfg,
-		cli: this.cli,
+		cfg:      this.cfg,
+		provider: this.provider,
 	}
 
 	r.AddSystemMessage(AnalyzeAgentSystemPrompt)
 	return r
 }
 
-func (this *AnalyzeSubAgent) Execute(ctx *Context, task *Task) (r *Result, err error) {
-	fmt.Printf("\t 🔬 正在通过已有信息分析...\n")
+// InsertedTasks 信息不足时插入一个补充检索任务以及重新执行的自身
+func (this *AnalyzeSubAgent) InsertedTasks() int {
+	return 2
+}
+
+func (this *AnalyzeSubAgent) Execute(c context.Context, ctx *Context, task *Task) (r *Result, err error) {
+	ctx.Infof(task, "🔬 正在通过已有信息分析...")
 	r = &Result{}
 
-	references := []string{}
-	for i, t := range ctx.Tasks {
-		if i >= ctx.Offset || len(t.Output) == 0 {
-			continue
-		}
-		references = append(references, fmt.Sprintf("Output from %s task:\n%s", t.Name, t.Output))
-	}
-	this.AddUserMessage(fmt.Sprintf(AnalyzeAgentUserPromptFormat, ctx.Input, task.Description, strings.Join(references, "\n\n")))
+	references := ctx.References(task, this.cfg.AllPrevious)
+	budget := NewTokenBudget(this.cfg, this.provider)
+	references = budget.Fit(c, ctx, task, budget.Available(this.messages, fmt.Sprintf(AnalyzeAgentUserPromptFormat, ctx.Query(), task.Description, "")), references)
+	this.AddUserMessage(fmt.Sprintf(AnalyzeAgentUserPromptFormat, ctx.Query(), task.Description, strings.Join(references, "\n\n")))
 
-	req := openai.ChatCompletionRequest{
+	req := &llm.Request{
 		Model:       this.cfg.Model,
 		Messages:    this.messages,
 		Temperature: 0,
 	}
-	util.IfDo(this.cfg.Verbose, func() { LogStruct("AnalyzeSubAgent LLM Request", req) })
 
-	var resp openai.ChatCompletionResponse
-	if resp, err = this.cli.CreateChatCompletion(context.Background(), req); err != nil {
+	var resp *llm.Response
+	if resp, err = this.chat(c, ctx, task, this.Name(), this.provider, req, false); err != nil {
 		err = fmt.Errorf("LLM 请求发生异常: %v", err)
 		return
 	}
-	util.IfDo(this.cfg.Verbose, func() { LogStruct("AnalyzeSubAgent LLM Response", resp) })
-	this.AddAssistantMessage(resp.Choices[0].Message.Content)
-
-	llmResp := TrimLLMResp(resp.Choices[0].Message.Content)
+	this.AddAssistantMessage(resp.Message.Content)
+
+	llmResp := TrimLLMResp(resp.Message.Content)
+	if strings.HasPrefix(llmResp, "MISSING_INFO:") && ctx.replanLimit != "" {
+		// 达到动态规划上限，要求 LLM 基于已有信息给出结果
+		ctx.Warnf(task, "⚠️ %s，不再补充检索，将基于已有信息完成分析", ctx.replanLimit)
+		this.AddUserMessage(AnalyzeAgentBestEffortPrompt)
+		req.Messages = this.messages
+		if resp, err = this.chat(c, ctx, task, this.Name(), this.provider, req, false); err != nil {
+			err = fmt.Errorf("LLM 请求发生异常: %v", err)
+			return
+		}
+		this.AddAssistantMessage(resp.Message.Content)
+		llmResp = strings.TrimSpace(strings.TrimPrefix(TrimLLMResp(resp.Message.Content), "MISSING_INFO:"))
+	}
 	if strings.HasPrefix(llmResp, "MISSING_INFO:") {
 		query := strings.TrimPrefix(llmResp, "MISSING_INFO:")
-		fmt.Printf("\t 🔄 分析信息不完整，正在补充检索: %s\n", query)
+		ctx.Infof(task, "🔄 分析信息不完整，正在补充检索: %s", query)
 
 		r.Tasks = append(r.Tasks, &Task{
 			Name:        "SearchSubAgent",
@@ -96,6 +106,6 @@ func (this *AnalyzeSubAgent) Execute(ctx *Context, task *Task) (r *Result, err e
 	}
 
 	r.Output = llmResp
-	fmt.Printf("\t 💬 分析完成\n")
+	ctx.Infof(task, "💬 分析完成")
 	return
 }
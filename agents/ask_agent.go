@@ -0,0 +1,177 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/ant-agent/llm"
+	"github.com/ant-libs-go/ant-agent/search"
+)
+
+const AskAgentSystemPrompt = `你是一个问答助手，负责基于一次研究中已经收集到的资料回答用户的追问。
+只能使用提供的资料作答，不要编造资料中不存在的事实。
+每条结论后使用资料的任务编号标注来源，例如 [t2]；资料中以 [n] 标注的来源编号也请一并保留，例如 [t2][5]。`
+const AskAgentUserPromptFormat = `此前的研究请求: %s
+用户的问题: %s
+
+已收集的资料（方括号中为任务编号）：
+%s
+%s`
+const AskAgentMissingPrompt = `
+如果资料不足以回答问题，请仅回复 'MISSING_INFO: <具体的搜索查询>'。`
+const AskAgentBestEffortPrompt = `
+无法再补充检索更多信息，请基于现有资料尽可能回答，并明确指出资料中缺少的部分。`
+
+var askCitationRegexp = regexp.MustCompile(`\[([^\[\]\s]+)\]`)
+
+// AskAgent 基于当前会话中各任务的输出回答追问，资料不足时补充检索一次，
+// 补充检索的结果作为新任务记入会话，供后续追问使用
+type AskAgent struct {
+	CommonAgent
+	cfg      *antagent.Config
+	provider llm.Provider
+	searcher search.Provider
+}
+
+func NewAskAgent(cfg *antagent.Config, provider llm.Provider, searcher search.Provider) (r *AskAgent) {
+	r = &AskAgent{
+		cfg:      cfg,
+		provider: provider,
+		searcher: searcher,
+	}
+
+	r.AddSystemMessage(AskAgentSystemPrompt)
+	return
+}
+
+func (this *AskAgent) Name() string {
+	return "AskAgent"
+}
+
+func (this *AskAgent) Description() string {
+	return "基于已收集的资料回答追问"
+}
+
+func (this *AskAgent) Clone() Agent {
+	return NewAskAgent(this.cfg, this.provider, this.searcher)
+}
+
+// Execute 回答 task.Description 中的问题
+func (this *AskAgent) Execute(c context.Context, ctx *Context, task *Task) (r *Result, err error) {
+	ctx.Infof(task, "💬 正在从已收集的资料中寻找答案...")
+	r = &Result{}
+
+	var answer string
+	if answer, err = this.answer(c, ctx, task, AskAgentMissingPrompt); err != nil {
+		return
+	}
+
+	if strings.HasPrefix(answer, "MISSING_INFO:") {
+		query := strings.TrimSpace(strings.TrimPrefix(answer, "MISSING_INFO:"))
+		ctx.Infof(task, "🔍 已收集的资料中没有答案，正在补充检索: %s", query)
+		if er := this.search(c, ctx, query); er != nil {
+			ctx.Warnf(task, "‼️ 补充检索失败，将基于已有资料回答: %v", er)
+		}
+		if answer, err = this.answer(c, ctx, task, AskAgentBestEffortPrompt); err != nil {
+			return
+		}
+		answer = strings.TrimSpace(strings.TrimPrefix(answer, "MISSING_INFO:"))
+	}
+
+	r.Output = answer + this.sources(ctx, answer)
+	return
+}
+
+func (this *AskAgent) answer(c context.Context, ctx *Context, task *Task, instruction string) (r string, err error) {
+	ctx.RLock()
+	references := []string{}
+	for _, t := range ctx.Tasks {
+		// 渲染结果与报告内容重复
+		if t.Output == "" || t.Name == "RenderSubAgent" {
+			continue
+		}
+		references = append(references, fmt.Sprintf("[%s] Output from %s task (%s):\n%s", t.Id, t.Name, t.Description, t.Output))
+	}
+	ctx.RUnlock()
+
+	// 每次回答都基于最新的资料重新构造对话
+	this.messages = this.messages[:1]
+	budget := NewTokenBudget(this.cfg, this.provider)
+	references = budget.Fit(c, ctx, task, budget.Available(this.messages, fmt.Sprintf(AskAgentUserPromptFormat, ctx.Input, task.Description, "", instruction)), references)
+	this.AddUserMessage(fmt.Sprintf(AskAgentUserPromptFormat, ctx.Input, task.Description, strings.Join(references, "\n\n"), instruction))
+
+	req := &llm.Request{
+		Model:       this.cfg.Model,
+		Messages:    this.messages,
+		Temperature: 0,
+	}
+
+	var resp *llm.Response
+	if resp, err = this.chat(c, ctx, task, this.Name(), this.provider, req, false); err != nil {
+		err = fmt.Errorf("LLM 请求发生异常: %v", err)
+		return
+	}
+	r = TrimLLMResp(resp.Message.Content)
+	return
+}
+
+// search 补充检索 query，并将结果作为已完成的任务追加到会话中
+func (this *AskAgent) search(c context.Context, ctx *Context, query string) (err error) {
+	task := &Task{
+		Name:        "SearchSubAgent",
+		Description: "补充检索: " + query,
+		Parameters:  map[string]interface{}{"query": query},
+		DependsOn:   []string{},
+	}
+
+	var result *Result
+	if result, err = NewSearchSubAgent(this.cfg, this.provider, this.searcher).Execute(c, ctx, task); err != nil {
+		return
+	}
+	task.Output = result.Output
+	task.Status = TaskStatusDone
+
+	ctx.Lock()
+	defer ctx.Unlock()
+	used := map[string]bool{}
+	for _, t := range ctx.Tasks {
+		used[t.Id] = true
+	}
+	for i := len(ctx.Tasks) + 1; task.Id == "" || used[task.Id]; i++ {
+		task.Id = fmt.Sprintf("t%d", i)
+	}
+	ctx.Tasks = append(ctx.Tasks, task)
+	return
+}
+
+// sources 列出回答中引用的任务与来源
+func (this *AskAgent) sources(ctx *Context, answer string) string {
+	ctx.RLock()
+	defer ctx.RUnlock()
+
+	tasks := map[string]*Task{}
+	for _, t := range ctx.Tasks {
+		tasks[t.Id] = t
+	}
+
+	var b strings.Builder
+	cited := map[string]bool{}
+	for _, m := range askCitationRegexp.FindAllStringSubmatch(answer, -1) {
+		t, ok := tasks[m[1]]
+		if !ok || cited[t.Id] {
+			continue
+		}
+		cited[t.Id] = true
+		b.WriteString(fmt.Sprintf("\n- [%s] %s: %s", t.Id, t.Name, t.Description))
+	}
+	for _, s := range ctx.Sources.Cited(answer) {
+		b.WriteString(fmt.Sprintf("\n- [%d] %s. %s", s.Id, s.Title, s.URL))
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "\n\n来源:" + b.String()
+}
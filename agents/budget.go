@@ -0,0 +1,219 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/ant-agent/llm"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	BudgetStrategyTruncate  = "truncate"
+	BudgetStrategySummarize = "summarize"
+
+	// 压缩后低于该 token 数的参考资料直接丢弃
+	minReferenceTokens = 200
+)
+
+const BudgetSummarizePromptFormat = `用户的重要指令/请求: %s
+当前任务目标：%s
+
+请将以下资料压缩到 %d 字以内，只保留与上述请求和任务相关的事实、数据与来源 URL，不要添加任何其他内容：
+%s`
+
+// TokenBudget 估算 prompt 的 token 数，在超出模型上下文窗口时按相关性对参考资料排序，
+// 并对放不下的参考资料进行截断或总结
+type TokenBudget struct {
+	cfg      *antagent.Config
+	provider llm.Provider
+}
+
+func NewTokenBudget(cfg *antagent.Config, provider llm.Provider) (r *TokenBudget) {
+	r = &TokenBudget{
+		cfg:      cfg,
+		provider: provider,
+	}
+	return
+}
+
+// Available 返回扣除固定 prompt 与预留输出后可用于参考资料的 token 数
+func (this *TokenBudget) Available(messages []openai.ChatCompletionMessage, prompt string) int {
+	used := llm.EstimateTokens(prompt) + this.cfg.OutputReserve
+	for _, msg := range messages {
+		used += llm.EstimateTokens(msg.Content)
+	}
+	return llm.ContextWindow(this.cfg.Model, this.cfg.ContextWindow) - used
+}
+
+// Fit 使 references 满足 available 的预算，结果保持原有顺序
+func (this *TokenBudget) Fit(c context.Context, ctx *Context, task *Task, available int, references []string) (r []string) {
+	sizes := make([]int, len(references))
+	total := 0
+	for i, ref := range references {
+		sizes[i] = llm.EstimateTokens(ref)
+		total += sizes[i]
+	}
+	if total <= available {
+		return references
+	}
+	ctx.Infof(task, "✂️ 参考资料约 %d tokens，超出上下文预算 %d tokens，正在压缩...", total, available)
+
+	// 按与请求及任务的相关性排序，相关性高的优先完整保留
+	terms := keywords(ctx.Input + " " + task.Description)
+	order := make([]int, len(references))
+	scores := make([]float64, len(references))
+	for i, ref := range references {
+		order[i] = i
+		scores[i] = relevance(terms, ref)
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	kept := make([]string, len(references))
+	remaining := available
+	overflow := []int{}
+	for _, i := range order {
+		if sizes[i] <= remaining {
+			kept[i] = references[i]
+			remaining -= sizes[i]
+			continue
+		}
+		overflow = append(overflow, i)
+	}
+
+	// 放不下的参考资料平分剩余预算，平分后过少时直接丢弃
+	if len(overflow) > 0 && remaining/len(overflow) >= minReferenceTokens {
+		share := remaining / len(overflow)
+		for _, i := range overflow {
+			kept[i] = this.compress(c, ctx, task, references[i], share)
+		}
+	} else if len(overflow) > 0 {
+		dropped := make([]string, 0, len(overflow))
+		for _, i := range overflow {
+			dropped = append(dropped, referenceName(references[i]))
+		}
+		ctx.Warnf(task, "⚠️ 上下文预算不足，丢弃了 %d 份参考资料: %s", len(dropped), strings.Join(dropped, "; "))
+	}
+
+	for _, ref := range kept {
+		if ref != "" {
+			r = append(r, ref)
+		}
+	}
+	ctx.Debugf(task, "TokenBudget: %d references, %d overflowed, %d kept", len(references), len(overflow), len(r))
+	return
+}
+
+func (this *TokenBudget) compress(c context.Context, ctx *Context, task *Task, ref string, tokens int) string {
+	if this.cfg.BudgetStrategy == BudgetStrategySummarize && this.provider != nil {
+		summary, err := this.summarize(c, ctx, task, ref, tokens)
+		if err == nil {
+			return summary
+		}
+		ctx.Warnf(task, "‼️ 参考资料总结失败，改为截断: %v", err)
+	}
+	return truncateTokens(ref, tokens)
+}
+
+func (this *TokenBudget) summarize(c context.Context, ctx *Context, task *Task, ref string, tokens int) (r string, err error) {
+	// 待总结的内容本身也可能超出窗口
+	limit := llm.ContextWindow(this.cfg.Model, this.cfg.ContextWindow) - this.cfg.OutputReserve - 500
+	if limit > 0 {
+		ref = truncateTokens(ref, limit)
+	}
+
+	req := &llm.Request{
+		Model: this.cfg.Model,
+		Messages: []openai.ChatCompletionMessage{{
+			Role:    openai.ChatMessageRoleUser,
+			Content: fmt.Sprintf(BudgetSummarizePromptFormat, ctx.Input, task.Description, tokens, ref),
+		}},
+		Temperature: 0,
+	}
+
+	var resp *llm.Response
+	if resp, err = this.provider.Chat(ctx.llmContext(c, task, "TokenBudget"), req); err != nil {
+		return
+	}
+	ctx.addLLMUsage("TokenBudget", req, resp)
+
+	r = truncateTokens(strings.TrimSpace(resp.Message.Content), tokens)
+	return
+}
+
+// referenceName 以参考资料的首行作为其名称，用于提示被丢弃的参考资料
+func referenceName(ref string) string {
+	name, _, _ := strings.Cut(strings.TrimSpace(ref), "\n")
+	name = strings.TrimSuffix(strings.TrimSpace(name), ":")
+	if runes := []rune(name); len(runes) > 60 {
+		name = string(runes[:60]) + "..."
+	}
+	return name
+}
+
+// truncateTokens 截断文本使其估算 token 数不超过 tokens
+func truncateTokens(text string, tokens int) string {
+	if llm.EstimateTokens(text) <= tokens {
+		return text
+	}
+
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if llm.EstimateTokens(string(runes[:mid])) <= tokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return string(runes[:lo]) + "\n...(内容过长已截断)"
+}
+
+// keywords 提取英文单词与中文双字词作为相关性计算的关键词
+func keywords(text string) (r map[string]bool) {
+	r = map[string]bool{}
+	var word []rune
+	var prev rune
+	flush := func() {
+		if len(word) > 1 {
+			r[strings.ToLower(string(word))] = true
+		}
+		word = word[:0]
+	}
+	for _, c := range text {
+		switch {
+		case unicode.Is(unicode.Han, c):
+			flush()
+			if unicode.Is(unicode.Han, prev) {
+				r[string([]rune{prev, c})] = true
+			}
+		case unicode.IsLetter(c) || unicode.IsDigit(c):
+			word = append(word, c)
+		default:
+			flush()
+		}
+		prev = c
+	}
+	flush()
+	return
+}
+
+// relevance 计算关键词在文本中的覆盖率
+func relevance(terms map[string]bool, text string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	text = strings.ToLower(text)
+	hits := 0
+	for term := range terms {
+		if strings.Contains(text, term) {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(terms))
+}
@@ -0,0 +1,108 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ConsoleEvents 将事件以文本形式输出到终端，verbose 为 true 时同时输出 LLM 请求与应答等调试信息
+type ConsoleEvents struct {
+	mu        sync.Mutex
+	w         io.Writer
+	verbose   bool
+	streaming map[string]bool // 正在实时输出内容的 agent
+}
+
+// NewConsoleEvents 创建输出到 w 的 ConsoleEvents，报告输出到标准输出时进度应输出到标准错误
+func NewConsoleEvents(w io.Writer, verbose bool) (r *ConsoleEvents) {
+	r = &ConsoleEvents{
+		w:         w,
+		verbose:   verbose,
+		streaming: map[string]bool{},
+	}
+	return
+}
+
+func (this *ConsoleEvents) Emit(e *Event) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	switch e.Type {
+	case EventPlanStart:
+		fmt.Fprintf(this.w, "🧠 正在规划你的任务...\n")
+	case EventPlan:
+		if result, ok := e.Data.(*Result); ok {
+			fmt.Fprintf(this.w, "📝 LLM 已经完成任务规划: \n")
+			for idx, task := range result.Tasks {
+				fmt.Fprintf(this.w, " %d. [%s] %s.\n", idx+1, task.Name, task.Description)
+			}
+		}
+	case EventReplan:
+		if data, ok := e.Data.(*ReplanEvent); ok && e.Task != nil {
+			fmt.Fprintf(this.w, "🔄 动态规划更新: 插入 %d 个新任务\n", len(data.Tasks))
+		} else {
+			fmt.Fprintf(this.w, "🔄 正在重新规划你的任务...\n")
+		}
+	case EventTaskStart:
+		if data, ok := e.Data.(*ProgressEvent); ok {
+			fmt.Fprintf(this.w, "📍 步骤 %d/%d: [%s] %s\n", data.Step, data.Total, e.Task.Name, e.Task.Description)
+		}
+	case EventTaskDone:
+		if data, ok := e.Data.(*ProgressEvent); ok {
+			fmt.Fprintf(this.w, "👍 任务[%s]运行成功，进度 %d/%d\n", e.Task.Name, data.Finished, data.Total)
+		}
+	case EventTaskFail:
+		fmt.Fprintf(this.w, "‼️ 任务[%s]执行失败: %v\n", e.Task.Name, e.Data)
+	case EventLLMRequest:
+		if data, ok := e.Data.(*LLMEvent); ok && this.verbose {
+			this.logStruct(data.Agent+" LLM Request", data.Request)
+		}
+	case EventLLMResponse:
+		data, ok := e.Data.(*LLMEvent)
+		if !ok {
+			return
+		}
+		// 实时输出结束后换行
+		if this.streaming[data.Agent] {
+			fmt.Fprintln(this.w)
+			delete(this.streaming, data.Agent)
+		}
+		if this.verbose && data.Response != nil {
+			this.logStruct(data.Agent+" LLM Response", data.Response)
+		}
+	case EventLLMRetry:
+		if data, ok := e.Data.(*RetryEvent); ok {
+			fmt.Fprintf(this.w, "⏳ LLM 请求失败，%v 后进行第 %d 次重试: %v\n", data.Delay.Round(time.Millisecond), data.Attempt, data.Error)
+		}
+	case EventDelta:
+		if data, ok := e.Data.(*DeltaEvent); ok && data.Echo {
+			fmt.Fprint(this.w, data.Content)
+			this.streaming[data.Agent] = true
+		}
+	case EventToolCall:
+		if data, ok := e.Data.(*ToolCallEvent); ok && this.verbose {
+			fmt.Fprintf(this.w, "%s ToolCall[%s]: %s\n", e.Task.Name, data.Name, data.Arguments)
+		}
+	case EventLog:
+		data, ok := e.Data.(*LogEvent)
+		if !ok || (data.Level == LogDebug && !this.verbose) {
+			return
+		}
+		if e.Task != nil {
+			fmt.Fprintf(this.w, "\t %s\n", data.Message)
+		} else {
+			fmt.Fprintf(this.w, "%s\n", data.Message)
+		}
+	case EventReport:
+		fmt.Fprintf(this.w, "\n📄 最终报告:\n")
+		fmt.Fprintf(this.w, "%v\n", e.Data)
+	}
+}
+
+func (this *ConsoleEvents) logStruct(prefix string, obj interface{}) {
+	b, _ := json.Marshal(obj)
+	fmt.Fprintf(this.w, "%s: %s\n", prefix, string(b))
+}
@@ -0,0 +1,108 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/ant-agent/fetch"
+	"github.com/ant-libs-go/util"
+)
+
+// 抓取站点时单个页面交给后续任务的最大字符数，页面较多，比 FetchSubAgent 更短
+const maxCrawlPageChars = 4000
+
+// CrawlSubAgent 从种子网址开始抓取同一站点的多个页面，适用于针对特定产品或文档站点的深入研究
+type CrawlSubAgent struct {
+	CommonAgent
+	cfg     *antagent.Config
+	fetcher *fetch.Fetcher
+}
+
+func NewCrawlSubAgent(cfg *antagent.Config, fetcher *fetch.Fetcher) (r *CrawlSubAgent) {
+	r = &CrawlSubAgent{
+		cfg:     cfg,
+		fetcher: fetcher,
+	}
+	return
+}
+
+func (this *CrawlSubAgent) Name() string {
+	return "CrawlSubAgent"
+}
+
+func (this *CrawlSubAgent) Description() string {
+	return "从参数 url 指定的网址开始抓取同一站点的页面（遵守 robots.txt 并读取 sitemap），适用于深入研究特定产品或文档站点，可选参数 depth 与 max_pages 限制链接层数与页面数"
+}
+
+func (this *CrawlSubAgent) RequiredParameters() []string {
+	return []string{"url"}
+}
+
+func (this *CrawlSubAgent) Clone() Agent {
+	return NewCrawlSubAgent(this.cfg, this.fetcher)
+}
+
+func (this *CrawlSubAgent) Execute(c context.Context, ctx *Context, task *Task) (r *Result, err error) {
+	seed, _ := task.Parameters["url"].(string)
+	opts := &fetch.CrawlOptions{
+		MaxDepth: task.IntParameter("depth", this.cfg.CrawlDepth),
+		MaxPages: task.IntParameter("max_pages", this.cfg.CrawlMaxPages),
+		OnPage: func(url string, page *fetch.Page, err error) {
+			switch {
+			case err != nil:
+				ctx.Warnf(task, "‼️ 页面[%s]抓取失败: %v", url, err)
+			case page.Cached:
+				ctx.Debugf(task, "🗄️ 页面[%s]命中缓存", url)
+			default:
+				ctx.Debugf(task, "📄 已抓取页面: %s", url)
+			}
+		},
+	}
+	ctx.Infof(task, "🕸️ 正在抓取站点 %s（最多 %d 层链接、%d 个页面）...", seed, opts.MaxDepth, opts.MaxPages)
+	r = &Result{}
+
+	var res *fetch.CrawlResult
+	res, err = fetch.NewCrawler(this.fetcher, opts).Crawl(c, seed)
+	if res == nil || len(res.Pages) == 0 {
+		err = fmt.Errorf("站点抓取失败: %v", util.If(err != nil, err, "没有抓取到可读的页面"))
+		return
+	}
+	// 任务超时时保留已抓取的页面；研究被取消时由调度器将任务重置为待执行，
+	// 已抓取的页面在缓存中，恢复后无需重新下载
+	if err != nil && errors.Is(context.Cause(c), ErrTaskTimeout) {
+		ctx.Warnf(task, "⚠️ 站点抓取超时，将使用已抓取的 %d 个页面", len(res.Pages))
+		err = nil
+	}
+	if err != nil {
+		err = fmt.Errorf("站点抓取中断: %v", err)
+		return
+	}
+
+	sources := make([]*Source, 0, len(res.Pages))
+	for _, page := range res.Pages {
+		content := page.Content
+		if runes := []rune(content); len(runes) > maxCrawlPageChars {
+			content = string(runes[:maxCrawlPageChars]) + "\n...(内容过长，已截断)"
+		}
+		sources = append(sources, &Source{
+			Title:       page.Title,
+			URL:         page.URL,
+			Snippet:     string([]rune(content)[:min(len([]rune(content)), 200)]),
+			Author:      page.Author,
+			PublishedAt: page.PublishedAt,
+			Content:     content,
+		})
+	}
+	r.Output = FormatSources(ctx.AddSources(sources))
+
+	summary := fmt.Sprintf("💬 抓取完成，共 %d 个页面", len(res.Pages))
+	util.IfDo(len(res.Failed) > 0, func() { summary += fmt.Sprintf("，%d 个失败", len(res.Failed)) })
+	util.IfDo(len(res.Disallowed) > 0, func() {
+		summary += fmt.Sprintf("，%d 个被 robots.txt 禁止: %s", len(res.Disallowed), strings.Join(res.Disallowed[:min(len(res.Disallowed), 3)], ", "))
+	})
+	ctx.Infof(task, "%s", summary)
+	return
+}
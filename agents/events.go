@@ -0,0 +1,163 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ant-libs-go/ant-agent/llm"
+)
+
+type EventType string
+
+const (
+	EventPlanStart   EventType = "plan_start"
+	EventPlan        EventType = "plan"
+	EventReplan      EventType = "replan"
+	EventTaskStart   EventType = "task_start"
+	EventTaskDone    EventType = "task_done"
+	EventTaskFail    EventType = "task_failed"
+	EventLLMRequest  EventType = "llm_request"
+	EventLLMResponse EventType = "llm_response"
+	EventLLMRetry    EventType = "llm_retry"
+	EventToolCall    EventType = "tool_call"
+	EventDelta       EventType = "llm_delta"
+	EventLog         EventType = "log"
+	EventReport      EventType = "report"
+)
+
+type Event struct {
+	Type EventType   `json:"type"`
+	Time time.Time   `json:"time"`
+	Task *Task       `json:"task,omitempty"` // 事件发生时任务的拷贝
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Events 接收执行过程中产生的事件，可能被多个任务并发调用，实现需要保证并发安全
+type Events interface {
+	Emit(e *Event)
+}
+
+// MultiEvents 将事件依次分发给多个订阅方
+type MultiEvents []Events
+
+func (this MultiEvents) Emit(e *Event) {
+	for _, events := range this {
+		if events != nil {
+			events.Emit(e)
+		}
+	}
+}
+
+// ProgressEvent 为任务开始与结束事件的数据，Step 为任务在计划中的序号
+type ProgressEvent struct {
+	Step     int `json:"step,omitempty"`
+	Finished int `json:"finished,omitempty"`
+	Total    int `json:"total"`
+}
+
+// ReplanEvent 为重新规划事件的数据，用户要求修改计划时 Feedback 为修改意见，
+// 任务动态插入新任务时 Tasks 为插入的任务
+type ReplanEvent struct {
+	Feedback string  `json:"feedback,omitempty"`
+	Tasks    []*Task `json:"tasks,omitempty"`
+}
+
+type LLMEvent struct {
+	Agent    string        `json:"agent"`
+	Request  *llm.Request  `json:"request,omitempty"`
+	Response *llm.Response `json:"response,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+type RetryEvent struct {
+	Agent   string        `json:"agent"`
+	Attempt int           `json:"attempt"`
+	Delay   time.Duration `json:"delay"`
+	Error   string        `json:"error"`
+}
+
+type DeltaEvent struct {
+	Agent   string `json:"agent"`
+	Content string `json:"content"`
+	Echo    bool   `json:"echo,omitempty"` // 面向用户的内容（规划、报告），界面应实时展示
+}
+
+type ToolCallEvent struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Error     string `json:"error,omitempty"`
+}
+
+type LogLevel string
+
+const (
+	LogDebug LogLevel = "debug"
+	LogInfo  LogLevel = "info"
+	LogWarn  LogLevel = "warn"
+)
+
+type LogEvent struct {
+	Level   LogLevel `json:"level"`
+	Message string   `json:"message"`
+}
+
+// Emit 向 ctx.Events 发送事件，task 会被拷贝以免订阅方读取到后续的修改
+func (this *Context) Emit(typ EventType, task *Task, data interface{}) {
+	if this.Events == nil {
+		return
+	}
+
+	e := &Event{Type: typ, Time: time.Now(), Data: data}
+	if task != nil {
+		cp := *task
+		e.Task = &cp
+	}
+	this.Events.Emit(e)
+}
+
+func (this *Context) Debugf(task *Task, format string, args ...interface{}) {
+	this.Emit(EventLog, task, &LogEvent{Level: LogDebug, Message: fmt.Sprintf(format, args...)})
+}
+
+func (this *Context) Infof(task *Task, format string, args ...interface{}) {
+	this.Emit(EventLog, task, &LogEvent{Level: LogInfo, Message: fmt.Sprintf(format, args...)})
+}
+
+func (this *Context) Warnf(task *Task, format string, args ...interface{}) {
+	this.Emit(EventLog, task, &LogEvent{Level: LogWarn, Message: fmt.Sprintf(format, args...)})
+}
+
+// llmContext 返回发起 LLM 请求使用的 context，请求重试时发送 EventLLMRetry
+func (this *Context) llmContext(c context.Context, task *Task, agent string) context.Context {
+	return llm.WithRetryObserver(c, func(attempt int, delay time.Duration, err error) {
+		this.Emit(EventLLMRetry, task, &RetryEvent{Agent: agent, Attempt: attempt, Delay: delay, Error: err.Error()})
+	})
+}
+
+// JSONEvents 将事件以 JSON Lines 格式写入 w
+type JSONEvents struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewJSONEvents(w io.Writer) (r *JSONEvents) {
+	r = &JSONEvents{
+		w: w,
+	}
+	return
+}
+
+func (this *JSONEvents) Emit(e *Event) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.w.Write(append(b, '\n'))
+}
@@ -0,0 +1,360 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/util"
+)
+
+// ErrTaskTimeout 为任务超出 Config.TaskTimeout 时 context 的 Cause，
+// subagent 可以据此区分任务超时与研究被取消，在超时时保留已得到的部分结果
+var ErrTaskTimeout = errors.New("task timeout")
+
+type Executor struct {
+	cfg     *antagent.Config
+	planner *PlanningAgent
+	seq     int
+	limited map[*Task]string // 因达到动态规划上限而重新执行的任务
+}
+
+type executeResult struct {
+	task     *Task
+	fork     *Context
+	result   *Result
+	err      error
+	canceled bool   // 任务因研究被取消而中断
+	limited  string // 动态插入的任务因达到上限被拒绝，任务将重新执行
+}
+
+func NewExecutor(cfg *antagent.Config, planner *PlanningAgent) (r *Executor) {
+	r = &Executor{
+		cfg:     cfg,
+		planner: planner,
+		limited: map[*Task]string{},
+	}
+	return
+}
+
+// Run 按照任务间的依赖关系调度 ctx.Tasks，所有依赖已结束的任务会并发执行，
+// 并发数受 Config.Concurrency 限制。c 被取消后不再调度新任务，正在执行的任务结束后返回，
+// 被中断的任务恢复为待执行状态，继续会话时会重新执行
+func (this *Executor) Run(c context.Context, ctx *Context) (err error) {
+	if this.cfg.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		c, cancel = context.WithTimeout(c, this.cfg.RunTimeout)
+		defer cancel()
+	}
+
+	ctx.Lock()
+	this.normalize(ctx.Tasks, ctx.Tasks, nil)
+	// 来源登记表由所有任务共享
+	if ctx.Sources == nil {
+		ctx.Sources = &Sources{}
+	}
+	ctx.Unlock()
+	this.checkpoint(ctx)
+
+	concurrency := this.cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	running := 0
+	ch := make(chan *executeResult)
+	for {
+		ctx.Lock()
+		for _, task := range this.readyTasks(ctx) {
+			if running >= concurrency || c.Err() != nil {
+				break
+			}
+			this.dispatch(c, ctx, task, ch)
+			running++
+		}
+		ctx.Unlock()
+
+		if running == 0 {
+			break
+		}
+
+		res := <-ch
+		running--
+
+		ctx.Lock()
+		progress, inserted := this.complete(ctx, res)
+		ctx.Unlock()
+
+		// 事件与检查点均在释放锁之后处理，订阅方可以安全地读取 ctx
+		if res.canceled {
+			ctx.Warnf(res.task, "⏹️ 任务[%s]已中断", res.task.Name)
+		} else if res.limited != "" {
+			ctx.Warnf(res.task, "⚠️ %s，任务[%s]将基于已有信息重新给出结果", res.limited, res.task.Name)
+		} else if res.err != nil {
+			ctx.Emit(EventTaskFail, res.task, res.err.Error())
+		} else {
+			if len(inserted) > 0 {
+				ctx.Emit(EventReplan, res.task, &ReplanEvent{Tasks: inserted})
+			}
+			ctx.Emit(EventTaskDone, res.task, progress)
+		}
+		this.checkpoint(ctx)
+	}
+
+	if errors.Is(c.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("研究超出最长运行时间 %v: %w", this.cfg.RunTimeout, c.Err())
+		return
+	}
+	if c.Err() != nil {
+		err = fmt.Errorf("研究已取消: %w", c.Err())
+		return
+	}
+
+	for _, task := range ctx.Tasks {
+		if !task.Finished() {
+			err = fmt.Errorf("任务[%s]存在无法满足的循环依赖", task.Id)
+			return
+		}
+	}
+	return
+}
+
+// dispatch 在新的 goroutine 中执行 task，单个任务的执行时间受 Config.TaskTimeout 限制
+func (this *Executor) dispatch(c context.Context, ctx *Context, task *Task, ch chan<- *executeResult) {
+	progress := &ProgressEvent{Step: this.indexOf(ctx.Tasks, task) + 1, Total: len(ctx.Tasks)}
+
+	var subagent Agent
+	if skill := this.planner.GetSkill(task.Name); skill != nil {
+		subagent = NewSkillSubAgent(this.cfg, this.planner.provider, skill)
+	} else {
+		subagent = this.planner.GetSubAgent(task.Name)
+	}
+
+	n := 1
+	if agent, ok := subagent.(ReplanningAgent); ok {
+		n = agent.InsertedTasks()
+	}
+
+	task.Status = TaskStatusRunning
+	fork := ctx.fork(task)
+	if fork.replanLimit = this.limited[task]; fork.replanLimit == "" {
+		fork.replanLimit = this.replanLimit(ctx, task, n)
+	}
+	started := *task
+	go func() {
+		ctx.Emit(EventTaskStart, &started, progress)
+		if subagent == nil {
+			ch <- &executeResult{task: task, fork: fork, err: fmt.Errorf("SubAgent[%s]未找到，请检查是否正确配置", task.Name)}
+			return
+		}
+
+		tc, cancel := c, context.CancelFunc(func() {})
+		if this.cfg.TaskTimeout > 0 {
+			tc, cancel = context.WithTimeoutCause(c, this.cfg.TaskTimeout, ErrTaskTimeout)
+		}
+		defer cancel()
+
+		result, err := subagent.Execute(tc, fork, task)
+		if err != nil && c.Err() == nil && errors.Is(tc.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("任务执行超时(%v): %v", this.cfg.TaskTimeout, err)
+		}
+		ch <- &executeResult{task: task, fork: fork, result: result, err: err, canceled: err != nil && c.Err() != nil}
+	}()
+}
+
+// complete 记录任务的执行结果，返回当前进度以及动态插入的任务的拷贝
+func (this *Executor) complete(ctx *Context, res *executeResult) (progress *ProgressEvent, inserted []*Task) {
+	task := res.task
+
+	// 失败的任务同样消耗了 token
+	if usage := res.fork.Usage.Total; usage.Calls > 0 {
+		task.Usage = &usage
+	}
+	if ctx.Usage == nil {
+		ctx.Usage = NewUsageStats()
+	}
+	ctx.Usage.Merge(res.fork.Usage)
+	if res.canceled {
+		task.Status = TaskStatusPending
+		return
+	}
+	if res.err != nil {
+		task.Status = TaskStatusFailed
+		return
+	}
+
+	// 动态规划，超出上限时拒绝插入并要求任务重新执行，重新执行后仍然插入的任务直接丢弃
+	if len(res.result.Tasks) > 0 && this.limited[task] == "" {
+		if res.limited = this.replanLimit(ctx, task, len(res.result.Tasks)); res.limited != "" {
+			this.limited[task] = res.limited
+			task.Status = TaskStatusPending
+			return
+		}
+		ctx.Replans++
+		for _, t := range this.insert(ctx, task, res.result.Tasks) {
+			cp := *t
+			inserted = append(inserted, &cp)
+		}
+	}
+	// 保留 subagent 的输出结果
+	task.Output = res.result.Output
+	task.Status = TaskStatusDone
+
+	finished := 0
+	for _, t := range ctx.Tasks {
+		if t.Finished() {
+			finished++
+		}
+	}
+	ctx.Offset = finished
+	progress = &ProgressEvent{Finished: finished, Total: len(ctx.Tasks)}
+	return
+}
+
+// replanLimit 检查 task 能否再动态插入 n 个任务，返回达到的上限，未达到时返回空字符串
+func (this *Executor) replanLimit(ctx *Context, task *Task, n int) string {
+	switch {
+	case this.cfg.MaxTaskReplans > 0 && task.Replans >= this.cfg.MaxTaskReplans:
+		return fmt.Sprintf("任务[%s]的动态规划次数已达到上限 %d", task.Name, this.cfg.MaxTaskReplans)
+	case this.cfg.MaxRunReplans > 0 && ctx.Replans >= this.cfg.MaxRunReplans:
+		return fmt.Sprintf("本次研究的动态规划次数已达到上限 %d", this.cfg.MaxRunReplans)
+	case this.cfg.MaxTotalTasks > 0 && len(ctx.Tasks)+n > this.cfg.MaxTotalTasks:
+		return fmt.Sprintf("计划的任务数已达到上限 %d", this.cfg.MaxTotalTasks)
+	}
+	return ""
+}
+
+// checkpoint 每个任务结束后保存会话，以便中断后可以继续执行
+func (this *Executor) checkpoint(ctx *Context) {
+	if this.cfg.SessionDir == "" {
+		return
+	}
+	if err := SaveSession(this.cfg.SessionDir, ctx); err != nil {
+		ctx.Warnf(nil, "‼️ 会话保存失败: %v", err)
+	}
+}
+
+// insert 将 origin 动态产生的新任务插入到其后方，并让依赖 origin 的任务改为等待新任务完成，返回实际插入的任务
+func (this *Executor) insert(ctx *Context, origin *Task, tasks []*Task) (inserted []*Task) {
+	inserted = make([]*Task, 0, len(tasks))
+	clones := map[*Task]bool{}
+	for _, t := range tasks {
+		if t != origin {
+			inserted = append(inserted, t)
+			continue
+		}
+		// 重新执行自身时生成新的任务
+		cp := &Task{
+			Name:        origin.Name,
+			Description: origin.Description,
+			Parameters:  origin.Parameters,
+			DependsOn:   append([]string{}, origin.DependsOn...),
+			AllPrevious: origin.AllPrevious,
+			Replans:     origin.Replans + 1,
+		}
+		clones[cp] = true
+		inserted = append(inserted, cp)
+	}
+
+	this.normalize(append(append([]*Task{}, ctx.Tasks...), inserted...), inserted, origin.DependsOn)
+
+	// 重新执行的任务额外依赖在其之前插入的任务
+	for i, t := range inserted {
+		if !clones[t] {
+			continue
+		}
+		for _, it := range inserted[:i] {
+			t.DependsOn = append(t.DependsOn, it.Id)
+		}
+	}
+
+	last := inserted[len(inserted)-1].Id
+	for _, t := range ctx.Tasks {
+		if t.Finished() {
+			continue
+		}
+		if exists, _ := util.InSlice(origin.Id, t.DependsOn); exists {
+			t.DependsOn = append(t.DependsOn, last)
+		}
+	}
+
+	idx := this.indexOf(ctx.Tasks, origin)
+	rear := append([]*Task{}, ctx.Tasks[idx+1:]...)
+	ctx.Tasks = append(ctx.Tasks[:idx+1], append(inserted, rear...)...)
+	return
+}
+
+// normalize 为缺少 id 或 id 重复的任务分配唯一 id，并将未声明依赖的任务设置为依赖前一个任务，
+// 首个任务未声明依赖时使用 deps
+func (this *Executor) normalize(all []*Task, tasks []*Task, deps []string) {
+	targets := map[*Task]bool{}
+	for _, t := range tasks {
+		targets[t] = true
+	}
+
+	used := map[string]bool{}
+	for _, t := range all {
+		if !targets[t] && t.Id != "" {
+			used[t.Id] = true
+		}
+	}
+
+	renames := []*Task{}
+	for _, t := range tasks {
+		if t.Id == "" || used[t.Id] {
+			renames = append(renames, t)
+			continue
+		}
+		used[t.Id] = true
+	}
+	for _, t := range renames {
+		for t.Id = ""; t.Id == "" || used[t.Id]; {
+			this.seq++
+			t.Id = fmt.Sprintf("t%d", this.seq)
+		}
+		used[t.Id] = true
+	}
+
+	prev := append([]string{}, deps...)
+	for _, t := range tasks {
+		if t.DependsOn == nil {
+			t.DependsOn = prev
+		}
+		prev = []string{t.Id}
+	}
+}
+
+func (this *Executor) readyTasks(ctx *Context) (r []*Task) {
+	status := map[string]TaskStatus{}
+	for _, t := range ctx.Tasks {
+		status[t.Id] = t.Status
+	}
+
+	for _, t := range ctx.Tasks {
+		if t.Status != TaskStatusPending {
+			continue
+		}
+		ready := true
+		for _, dep := range t.DependsOn {
+			// 未知的依赖直接忽略，失败的依赖视为已结束
+			if s, ok := status[dep]; ok && s != TaskStatusDone && s != TaskStatusFailed {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			r = append(r, t)
+		}
+	}
+	return
+}
+
+func (this *Executor) indexOf(tasks []*Task, task *Task) int {
+	for i, t := range tasks {
+		if t == task {
+			return i
+		}
+	}
+	return -1
+}
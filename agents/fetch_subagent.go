@@ -0,0 +1,144 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/ant-agent/fetch"
+	"github.com/ant-libs-go/util"
+)
+
+const (
+	// 单个页面交给后续任务的最大字符数，超出部分被截断
+	maxPageChars = 12000
+	// 同时下载的页面数上限
+	fetchConcurrency = 4
+)
+
+// FetchSubAgent 下载网页全文并提取正文，提取结果作为来源登记到会话中
+type FetchSubAgent struct {
+	CommonAgent
+	cfg     *antagent.Config
+	fetcher *fetch.Fetcher
+}
+
+func NewFetchSubAgent(cfg *antagent.Config, fetcher *fetch.Fetcher) (r *FetchSubAgent) {
+	r = &FetchSubAgent{
+		cfg:     cfg,
+		fetcher: fetcher,
+	}
+	return
+}
+
+func (this *FetchSubAgent) Name() string {
+	return "FetchSubAgent"
+}
+
+func (this *FetchSubAgent) Description() string {
+	return "下载网页全文并提取正文（支持 HTML、PDF 与纯文本），可选参数 urls 指定网址列表，未指定时下载所依赖检索任务中排名靠前的结果，数量由可选参数 top 指定"
+}
+
+func (this *FetchSubAgent) Clone() Agent {
+	return NewFetchSubAgent(this.cfg, this.fetcher)
+}
+
+func (this *FetchSubAgent) Execute(c context.Context, ctx *Context, task *Task) (r *Result, err error) {
+	ctx.Infof(task, "🌐 正在下载网页全文...")
+	r = &Result{}
+
+	urls := this.urls(ctx, task)
+	if len(urls) == 0 {
+		err = errors.New("没有可下载的网址，请通过 urls 参数指定或依赖一个检索任务")
+		return
+	}
+
+	pages := make([]*Source, len(urls))
+	errs := make([]error, len(urls))
+	sem := make(chan struct{}, fetchConcurrency)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			pages[i], errs[i] = this.fetch(c, ctx, task, u)
+		}()
+	}
+	wg.Wait()
+
+	fetched := []*Source{}
+	for i, page := range pages {
+		if errs[i] != nil {
+			ctx.Warnf(task, "‼️ 网页[%s]下载失败: %v", urls[i], errs[i])
+			continue
+		}
+		fetched = append(fetched, page)
+	}
+	if len(fetched) == 0 {
+		err = fmt.Errorf("网页下载失败: %v", errs[0])
+		return
+	}
+
+	r.Output = FormatSources(ctx.AddSources(fetched))
+	ctx.Infof(task, "💬 已下载 %d/%d 个网页", len(fetched), len(urls))
+	return
+}
+
+func (this *FetchSubAgent) fetch(c context.Context, ctx *Context, task *Task, url string) (r *Source, err error) {
+	var page *fetch.Page
+	if page, err = this.fetcher.Fetch(c, url); err != nil {
+		return
+	}
+	util.IfDo(page.Cached, func() { ctx.Debugf(task, "🗄️ 网页[%s]命中缓存", url) })
+
+	content := page.Content
+	if runes := []rune(content); len(runes) > maxPageChars {
+		content = string(runes[:maxPageChars]) + "\n...(内容过长，已截断)"
+	} else if page.Truncated {
+		content += "\n...(页面超过下载大小限制，已截断)"
+	}
+
+	// 以请求的网址登记，与检索结果中的来源共用编号
+	r = &Source{
+		Title:       page.Title,
+		URL:         url,
+		Snippet:     string([]rune(content)[:min(len([]rune(content)), 200)]),
+		Author:      page.Author,
+		PublishedAt: page.PublishedAt,
+		Content:     content,
+	}
+	return
+}
+
+// urls 返回需要下载的网址：优先使用 urls 参数，否则取所依赖任务中引用的、尚未下载过的前 top 个来源
+func (this *FetchSubAgent) urls(ctx *Context, task *Task) (r []string) {
+	switch v := task.Parameters["urls"].(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				r = append(r, strings.TrimSpace(s))
+			}
+		}
+	case string:
+		r = strings.FieldsFunc(v, func(c rune) bool { return c == ',' || c == '，' || c == ' ' || c == '\n' })
+	}
+	if len(r) > 0 {
+		return
+	}
+
+	top := task.IntParameter("top", this.cfg.FetchTop)
+	for _, s := range ctx.Sources.Cited(strings.Join(ctx.References(task, this.cfg.AllPrevious), "\n")) {
+		if len(r) >= top {
+			break
+		}
+		if s.Content == "" && (strings.HasPrefix(s.URL, "http://") || strings.HasPrefix(s.URL, "https://")) {
+			r = append(r, s.URL)
+		}
+	}
+	return
+}
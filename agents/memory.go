@@ -0,0 +1,155 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/ant-agent/llm"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	// 报告超过该 token 数时先进行摘要再记入记忆
+	memoryReportTokens = 300
+)
+
+const MemoryQueryFormat = `%s
+
+以下是此前的对话记忆，当前请求中的指代（如“那个”、“上面提到的”）请据此理解：
+%s`
+
+const MemoryReportPromptFormat = `用户的请求: %s
+
+请将以下研究报告压缩到 %d 字以内，只保留核心结论、关键实体与数据，不要添加任何其他内容：
+%s`
+
+const MemorySummarizePromptFormat = `请将以下多轮对话记忆压缩为一段 %d 字以内的摘要，保留每轮的请求主题、核心结论、关键实体与数据，不要添加任何其他内容：
+%s`
+
+// Turn 为一轮研究的记忆
+type Turn struct {
+	Query  string `json:"query"`
+	Plans  string `json:"plans,omitempty"`
+	Report string `json:"report,omitempty"` // 报告摘要
+}
+
+// Memory 为跨研究请求的对话记忆，较早的对话被压缩进 Summary
+type Memory struct {
+	Summary string  `json:"summary,omitempty"`
+	Turns   []*Turn `json:"turns,omitempty"`
+}
+
+func (this *Memory) String() string {
+	if this == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	if this.Summary != "" {
+		b.WriteString(fmt.Sprintf("更早的对话摘要: %s\n", this.Summary))
+	}
+	for _, t := range this.Turns {
+		b.WriteString(fmt.Sprintf("\n请求: %s\n", t.Query))
+		if t.Plans != "" {
+			b.WriteString(fmt.Sprintf("计划:\n%s\n", t.Plans))
+		}
+		if t.Report != "" {
+			b.WriteString(fmt.Sprintf("结论: %s\n", t.Report))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Query 返回附带对话记忆的用户请求，用于填充各个 agent 的提示词
+func (this *Context) Query() string {
+	if m := this.Memory.String(); m != "" {
+		return fmt.Sprintf(MemoryQueryFormat, this.Input, m)
+	}
+	return this.Input
+}
+
+// Memorizer 在每轮研究结束后将请求、计划与报告摘要记入 ctx.Memory，
+// 记忆超出 Config.MemoryTokens 时将较早的对话压缩为摘要
+type Memorizer struct {
+	cfg      *antagent.Config
+	provider llm.Provider
+}
+
+func NewMemorizer(cfg *antagent.Config, provider llm.Provider) (r *Memorizer) {
+	r = &Memorizer{
+		cfg:      cfg,
+		provider: provider,
+	}
+	return
+}
+
+// Remember 记录一轮对话，tasks 为本轮执行的任务，LLM 直接回复或追问时为空
+func (this *Memorizer) Remember(c context.Context, ctx *Context, query string, tasks []*Task, report string) {
+	if this.cfg.MemoryTokens <= 0 {
+		return
+	}
+
+	ctx.RLock()
+	memory := &Memory{}
+	if ctx.Memory != nil {
+		memory.Summary = ctx.Memory.Summary
+		memory.Turns = append(memory.Turns, ctx.Memory.Turns...)
+	}
+	turn := &Turn{Query: query}
+	for idx, t := range tasks {
+		turn.Plans += fmt.Sprintf("%d. [%s] %s\n", idx+1, t.Name, t.Description)
+	}
+	ctx.RUnlock()
+	turn.Plans = strings.TrimSpace(turn.Plans)
+
+	turn.Report = report
+	if llm.EstimateTokens(report) > memoryReportTokens {
+		var err error
+		if turn.Report, err = this.summarize(c, ctx, fmt.Sprintf(MemoryReportPromptFormat, query, memoryReportTokens, report), memoryReportTokens); err != nil {
+			ctx.Warnf(nil, "‼️ 报告摘要失败，将截断后记入对话记忆: %v", err)
+			turn.Report = truncateTokens(report, memoryReportTokens)
+		}
+	}
+	memory.Turns = append(memory.Turns, turn)
+
+	// 保留最近一轮对话原文，其余压缩为摘要
+	if llm.EstimateTokens(memory.String()) > this.cfg.MemoryTokens && len(memory.Turns) > 1 {
+		ctx.Debugf(nil, "🧠 对话记忆超出 %d tokens，正在压缩较早的对话...", this.cfg.MemoryTokens)
+		last := memory.Turns[len(memory.Turns)-1]
+		memory.Turns = memory.Turns[:len(memory.Turns)-1]
+
+		tokens := this.cfg.MemoryTokens / 2
+		summary, err := this.summarize(c, ctx, fmt.Sprintf(MemorySummarizePromptFormat, tokens, memory.String()), tokens)
+		if err != nil {
+			ctx.Warnf(nil, "‼️ 对话记忆压缩失败，将丢弃较早的对话: %v", err)
+			summary = truncateTokens(memory.Summary, tokens)
+		}
+		memory = &Memory{Summary: summary, Turns: []*Turn{last}}
+	}
+
+	ctx.Lock()
+	ctx.Memory = memory
+	ctx.Unlock()
+}
+
+func (this *Memorizer) summarize(c context.Context, ctx *Context, prompt string, tokens int) (r string, err error) {
+	req := &llm.Request{
+		Model: this.cfg.Model,
+		Messages: []openai.ChatCompletionMessage{{
+			Role:    openai.ChatMessageRoleUser,
+			Content: prompt,
+		}},
+		Temperature: 0,
+	}
+
+	var resp *llm.Response
+	if resp, err = this.provider.Chat(ctx.llmContext(c, nil, "Memorizer"), req); err != nil {
+		return
+	}
+	ctx.addLLMUsage("Memorizer", req, resp)
+
+	r = truncateTokens(TrimLLMResp(resp.Message.Content), tokens)
+	return
+}
@@ -0,0 +1,469 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type planEditorMode int
+
+const (
+	planEditorList planEditorMode = iota
+	planEditorDescription
+	planEditorParameters
+	planEditorPick
+	planEditorFeedback
+)
+
+const PlanEditorHelp = "↑/↓ 选择  shift+↑/↓ 移动  d 删除  c 复制  e 编辑描述  p 编辑参数  a 新增  r 用自然语言修改  enter 确认  esc 取消"
+
+var (
+	planEditorCursorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#C49C7B")).Bold(true)
+	planEditorMutedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#777777"))
+	planEditorErrorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#E06C75"))
+)
+
+// PlanEditorModel 为确认规划结果的终端编辑器，支持删除、移动、复制、编辑与新增任务，
+// 也可以改为输入自然语言的修改意见由 LLM 重新规划
+type PlanEditorModel struct {
+	tasks    []*Task
+	names    []string
+	validate func(tasks []*Task) []string
+
+	mode   planEditorMode
+	cursor int
+	pick   int
+	input  textinput.Model
+	status string
+	force  bool  // 校验未通过时再次确认则强制通过
+	added  *Task // 正在填写描述的新增任务，取消填写时丢弃
+
+	initial string // 打开编辑器时的计划，用于判断计划是否被修改
+
+	approved bool
+	feedback string
+	canceled bool
+}
+
+func NewPlanEditorModel(tasks []*Task, names []string, validate func(tasks []*Task) []string) PlanEditorModel {
+	ti := textinput.New()
+	ti.CharLimit = 1024
+	ti.Width = 100
+
+	r := PlanEditorModel{
+		names:    names,
+		validate: validate,
+		input:    ti,
+	}
+	for _, t := range tasks {
+		cp := *t
+		if t.DependsOn != nil {
+			cp.DependsOn = append([]string{}, t.DependsOn...)
+		}
+		r.tasks = append(r.tasks, &cp)
+	}
+	// 新增与复制的任务需要通过 id 建立依赖
+	for _, t := range r.tasks {
+		if t.Id == "" {
+			t.Id = r.nextId()
+		}
+	}
+	// 未声明依赖的任务依赖前一个任务，显式地写出后移动任务时才能调整依赖
+	for i, t := range r.tasks {
+		if t.DependsOn != nil {
+			continue
+		}
+		t.DependsOn = []string{}
+		if i > 0 {
+			t.DependsOn = []string{r.tasks[i-1].Id}
+		}
+	}
+	b, _ := json.Marshal(r.tasks)
+	r.initial = string(b)
+	return r
+}
+
+func (this PlanEditorModel) Init() tea.Cmd {
+	return nil
+}
+
+func (this PlanEditorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		this.input, cmd = this.input.Update(msg)
+		return this, cmd
+	}
+	if key.Type == tea.KeyCtrlC {
+		this.canceled = true
+		return this, tea.Quit
+	}
+
+	switch this.mode {
+	case planEditorList:
+		return this.updateList(key)
+	case planEditorPick:
+		return this.updatePick(key)
+	}
+	return this.updateInput(key)
+}
+
+func (this PlanEditorModel) updateList(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.String() != "enter" && key.String() != "y" {
+		this.force = false
+	}
+	this.status = ""
+
+	switch key.String() {
+	case "up", "k":
+		if this.cursor > 0 {
+			this.cursor--
+		}
+	case "down", "j":
+		if this.cursor < len(this.tasks)-1 {
+			this.cursor++
+		}
+	case "shift+up", "K":
+		if this.cursor > 0 {
+			this.move(this.cursor - 1)
+			this.cursor--
+		}
+	case "shift+down", "J":
+		if this.cursor < len(this.tasks)-1 {
+			this.move(this.cursor)
+			this.cursor++
+		}
+	case "d", "delete":
+		this.delete()
+	case "c":
+		if len(this.tasks) > 0 {
+			origin := this.tasks[this.cursor]
+			cp := *origin
+			cp.Id = this.nextId()
+			if origin.DependsOn != nil {
+				cp.DependsOn = append([]string{}, origin.DependsOn...)
+			}
+			this.insertAfter(origin, &cp)
+		}
+	case "e":
+		if len(this.tasks) > 0 {
+			this.edit(planEditorDescription, this.tasks[this.cursor].Description)
+		}
+	case "p":
+		if len(this.tasks) > 0 {
+			params := "{}"
+			if len(this.tasks[this.cursor].Parameters) > 0 {
+				b, _ := json.Marshal(this.tasks[this.cursor].Parameters)
+				params = string(b)
+			}
+			this.edit(planEditorParameters, params)
+		}
+	case "a":
+		if len(this.names) > 0 {
+			this.mode = planEditorPick
+			this.pick = 0
+		}
+	case "r":
+		this.edit(planEditorFeedback, "")
+	case "enter", "y":
+		return this.approve()
+	case "esc", "q":
+		this.canceled = true
+		return this, tea.Quit
+	}
+	return this, nil
+}
+
+func (this PlanEditorModel) updatePick(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.String() {
+	case "up", "k":
+		if this.pick > 0 {
+			this.pick--
+		}
+	case "down", "j":
+		if this.pick < len(this.names)-1 {
+			this.pick++
+		}
+	case "enter":
+		task := &Task{Id: this.nextId(), Name: this.names[this.pick]}
+		if len(this.tasks) == 0 {
+			this.tasks = []*Task{task}
+		} else {
+			origin := this.tasks[this.cursor]
+			task.DependsOn = []string{origin.Id}
+			this.insertAfter(origin, task)
+		}
+		this.added = task
+		this.edit(planEditorDescription, "")
+	case "esc":
+		this.mode = planEditorList
+	}
+	return this, nil
+}
+
+func (this PlanEditorModel) updateInput(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.Type {
+	case tea.KeyEsc:
+		// 新增任务时取消填写描述，则不再新增该任务
+		if this.added != nil {
+			this.delete()
+			this.added = nil
+		}
+		this.mode = planEditorList
+		this.status = ""
+		this.input.Blur()
+		return this, nil
+	case tea.KeyEnter:
+		value := strings.TrimSpace(this.input.Value())
+		switch this.mode {
+		case planEditorDescription:
+			if value == "" && this.added != nil {
+				this.status = "请填写新增任务的描述，或按 esc 取消新增"
+				return this, nil
+			}
+			this.tasks[this.cursor].Description = value
+			this.added = nil
+		case planEditorParameters:
+			params := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(value), &params); err != nil {
+				this.status = fmt.Sprintf("参数必须为 JSON 对象: %v", err)
+				return this, nil
+			}
+			this.tasks[this.cursor].Parameters = params
+		case planEditorFeedback:
+			if value == "" {
+				return this, nil
+			}
+			this.feedback = value
+			return this, tea.Quit
+		}
+		this.mode = planEditorList
+		this.input.Blur()
+		return this, nil
+	}
+
+	var cmd tea.Cmd
+	this.input, cmd = this.input.Update(key)
+	return this, cmd
+}
+
+func (this PlanEditorModel) approve() (tea.Model, tea.Cmd) {
+	if len(this.tasks) == 0 {
+		this.status = "计划中没有任务，请新增任务或用自然语言修改"
+		return this, nil
+	}
+	if this.validate != nil && !this.force {
+		if violations := this.validate(this.tasks); len(violations) > 0 {
+			this.status = "计划存在以下问题，再次按 enter 强制确认:\n - " + strings.Join(violations, "\n - ")
+			this.force = true
+			return this, nil
+		}
+	}
+	this.approved = true
+	return this, tea.Quit
+}
+
+func (this *PlanEditorModel) edit(mode planEditorMode, value string) {
+	this.mode = mode
+	this.status = ""
+	this.input.SetValue(value)
+	this.input.CursorEnd()
+	this.input.Focus()
+}
+
+// delete 删除当前任务，依赖该任务的任务改为依赖其依赖
+func (this *PlanEditorModel) delete() {
+	if len(this.tasks) == 0 {
+		return
+	}
+	removed := this.tasks[this.cursor]
+	this.tasks = append(this.tasks[:this.cursor], this.tasks[this.cursor+1:]...)
+	for _, t := range this.tasks {
+		deps := []string{}
+		changed := false
+		for _, dep := range t.DependsOn {
+			if dep != removed.Id {
+				deps = appendUnique(deps, dep)
+				continue
+			}
+			changed = true
+			for _, d := range removed.DependsOn {
+				deps = appendUnique(deps, d)
+			}
+		}
+		if changed {
+			t.DependsOn = deps
+		}
+	}
+	if this.cursor >= len(this.tasks) && this.cursor > 0 {
+		this.cursor--
+	}
+}
+
+// move 交换第 i 与第 i+1 个任务的位置。后者直接依赖前者时同时交换二者在依赖关系中的位置：
+// 后者改为依赖前者的依赖，前者改为依赖后者，原先依赖后者的任务改为依赖前者，
+// 否则二者相互独立，移动仅调整显示顺序
+func (this *PlanEditorModel) move(i int) {
+	prev, next := this.tasks[i], this.tasks[i+1]
+	this.tasks[i], this.tasks[i+1] = next, prev
+
+	dependent := false
+	deps := []string{}
+	for _, dep := range next.DependsOn {
+		if dep == prev.Id {
+			dependent = true
+			continue
+		}
+		deps = appendUnique(deps, dep)
+	}
+	if !dependent {
+		this.status = fmt.Sprintf("任务 %s 与 %s 之间没有直接依赖，移动仅调整显示顺序", prev.Id, next.Id)
+		return
+	}
+	for _, dep := range prev.DependsOn {
+		deps = appendUnique(deps, dep)
+	}
+
+	for _, t := range this.tasks {
+		if t == prev || t == next {
+			continue
+		}
+		rewired := []string{}
+		for _, dep := range t.DependsOn {
+			if dep == next.Id {
+				dep = prev.Id
+			}
+			rewired = appendUnique(rewired, dep)
+		}
+		t.DependsOn = rewired
+	}
+	next.DependsOn = deps
+	prev.DependsOn = []string{next.Id}
+}
+
+// insertAfter 将 task 插入到 origin 之后，依赖 origin 的任务同时依赖新任务
+func (this *PlanEditorModel) insertAfter(origin *Task, task *Task) {
+	for _, t := range this.tasks {
+		for _, dep := range t.DependsOn {
+			if dep == origin.Id {
+				t.DependsOn = appendUnique(t.DependsOn, task.Id)
+				break
+			}
+		}
+	}
+	idx := this.cursor + 1
+	this.tasks = append(this.tasks[:idx], append([]*Task{task}, this.tasks[idx:]...)...)
+	this.cursor = idx
+}
+
+func (this *PlanEditorModel) nextId() string {
+	used := map[string]bool{}
+	for _, t := range this.tasks {
+		used[t.Id] = true
+	}
+	for i := len(this.tasks) + 1; ; i++ {
+		if id := fmt.Sprintf("t%d", i); !used[id] {
+			return id
+		}
+	}
+}
+
+func (this PlanEditorModel) View() string {
+	var b strings.Builder
+	b.WriteString("\n📝 编辑计划\n")
+	b.WriteString(planEditorMutedStyle.Render(PlanEditorHelp) + "\n\n")
+
+	for idx, t := range this.tasks {
+		line := fmt.Sprintf("%d. [%s] %s", idx+1, t.Name, t.Description)
+		if len(t.Parameters) > 0 {
+			params, _ := json.Marshal(t.Parameters)
+			line += planEditorMutedStyle.Render(" " + string(params))
+		}
+		if len(t.DependsOn) > 0 {
+			line += planEditorMutedStyle.Render(fmt.Sprintf(" (%s ← %s)", t.Id, strings.Join(t.DependsOn, ", ")))
+		} else {
+			line += planEditorMutedStyle.Render(fmt.Sprintf(" (%s)", t.Id))
+		}
+		if idx == this.cursor {
+			b.WriteString(planEditorCursorStyle.Render("> ") + line + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+
+	switch this.mode {
+	case planEditorPick:
+		b.WriteString("\n选择要新增的 Skill 或 SubAgent (enter 确认, esc 取消):\n")
+		for idx, name := range this.names {
+			if idx == this.pick {
+				b.WriteString(planEditorCursorStyle.Render("> "+name) + "\n")
+			} else {
+				b.WriteString("  " + name + "\n")
+			}
+		}
+	case planEditorDescription:
+		b.WriteString("\n任务描述 (enter 保存, esc 取消):\n" + this.input.View() + "\n")
+	case planEditorParameters:
+		b.WriteString("\n任务参数 JSON (enter 保存, esc 取消):\n" + this.input.View() + "\n")
+	case planEditorFeedback:
+		b.WriteString("\n修改意见，将由 LLM 重新规划 (enter 提交, esc 取消):\n" + this.input.View() + "\n")
+	}
+
+	if this.status != "" {
+		b.WriteString("\n" + planEditorErrorStyle.Render(this.status) + "\n")
+	}
+	return b.String()
+}
+
+// EditorApprover 在终端中打开计划编辑器，用户调整后的任务会写回 result.Tasks；
+// 选择自然语言修改时，调整后的计划会附带在修改意见中
+func (this *PlanningAgent) EditorApprover(c context.Context, ctx *Context, result *Result) (approved bool, feedback string, err error) {
+	validate := func(tasks []*Task) []string {
+		return this.Validate(&Result{Tasks: tasks})
+	}
+
+	var m tea.Model
+	if m, err = tea.NewProgram(NewPlanEditorModel(result.Tasks, this.names(), validate), tea.WithContext(c)).Run(); err != nil {
+		err = fmt.Errorf("计划编辑器异常: %v", err)
+		return
+	}
+	editor, ok := m.(PlanEditorModel)
+	if !ok {
+		err = fmt.Errorf("unknown model type")
+		return
+	}
+	if editor.canceled {
+		err = fmt.Errorf("用户取消了计划确认")
+		return
+	}
+
+	b, _ := json.Marshal(editor.tasks)
+	edited := string(b) != editor.initial
+	result.Tasks = editor.tasks
+	if editor.approved {
+		approved = true
+		return
+	}
+
+	feedback = editor.feedback
+	if edited {
+		b, _ = json.Marshal(result)
+		feedback = fmt.Sprintf("我已将计划手动调整为:\n%s\n在此基础上，%s", b, feedback)
+	}
+	return
+}
+
+func appendUnique(s []string, v string) []string {
+	for _, it := range s {
+		if it == v {
+			return s
+		}
+	}
+	return append(s, v)
+}
@@ -4,10 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/ant-agent/llm"
 	"github.com/ant-libs-go/ant-agent/skills"
-	"github.com/ant-libs-go/util"
 	openai "github.com/sashabaranov/go-openai"
 )
 
@@ -25,20 +26,24 @@ const PlanningAgentSystemPrompt = `
 %s
 
 ## 对于给定的用户请求，创建一个包含任务序列的计划。每个任务应包含：
+- id: 任务的唯一标识 (例如: "t1")
 - name: 任意一个 Skill 或 SubAgent 的名称
 - description:  Skill 或 SubAgent 应该做什么
 - parameters: 任务的可选参数 (例如: {"query": "搜索词"})
+- depends_on: 该任务依赖的任务 id 列表，没有依赖时为空数组 []。任务只能读取到其所依赖任务的输出
+- all_previous: 可选，为 true 时任务可以读取之前所有任务的输出，仅在确实需要全部上下文时使用
 
 ## 仅返回具有此结构的有效 JSON 对象：
 {
   "output": "总体计划描述",
   "tasks": [
-    {"name": "CodeReviewSkill", "description": "..."},
-    {"name": "SearchSubAgent", "description": "...", "parameters": {"query": "..."}},
-    {"name": "AnalyzeSubAgent", "description": "..."},
-    {"name": "ReportSubAgent", "description": "..."},
-    {"name": "PPTSubAgent", "description": "根据报告生成幻灯片"},
-    {"name": "RenderSubAgent", "description": "渲染报告"}
+    {"id": "t1", "name": "CodeReviewSkill", "description": "...", "depends_on": []},
+    {"id": "t2", "name": "SearchSubAgent", "description": "...", "parameters": {"query": "..."}, "depends_on": []},
+    {"id": "t3", "name": "SearchSubAgent", "description": "...", "parameters": {"query": "..."}, "depends_on": []},
+    {"id": "t4", "name": "AnalyzeSubAgent", "description": "...", "depends_on": ["t1", "t2", "t3"]},
+    {"id": "t5", "name": "ReportSubAgent", "description": "...", "depends_on": ["t4"]},
+    {"id": "t6", "name": "PPTSubAgent", "description": "根据报告生成幻灯片", "depends_on": ["t5"]},
+    {"id": "t7", "name": "RenderSubAgent", "description": "渲染报告", "depends_on": ["t5"]}
   ]
 }
 
@@ -46,25 +51,69 @@ const PlanningAgentSystemPrompt = `
 - 仅在用户明确请求幻灯片或演示文稿时包含 PPT 任务。
 - 在 REPORT 任务之后始终包含 RENDER 任务，以生成最终的文本报告。
 - 如果判定用户请求不需要进行任务规划，返回结果中指定 output 为回复用户的内容且 tasks 为空， 否则返回 tasks 且 output 为空。
+- 检索结果只包含摘要。需要依据原文细节（数据、论证、文档内容）时，在 SearchSubAgent 之后加入依赖它的 FetchSubAgent 下载排名靠前的网页全文，分析任务同时依赖这两个任务。
+- 相互独立的任务（例如不同主题的检索）不要相互依赖，它们会被并发执行；需要使用其他任务输出的任务必须在 depends_on 中声明，只声明真正需要的输入，避免引入无关信息。
 - 保持计划简单且重点突出。通常 3-8 个任务就足够了。`
 
+const PlanningAgentRepairPromptFormat = `你上一次返回的内容无法解析为规定结构的 JSON: %v
+请修正后重新返回，仅返回 JSON 对象，不要包含任何解释说明或代码块标记。`
+
+const PlanningAgentValidatePromptFormat = `你上一次返回的计划存在以下问题：
+%s
+请修正后重新返回完整的计划，仅返回 JSON 对象。`
+
+// PlanningAgentResultSchema 为规划结果的 JSON Schema，parameters 为任意对象因此不使用 strict 模式
+var PlanningAgentResultSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "output": {"type": "string"},
+    "tasks": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"},
+          "name": {"type": "string"},
+          "description": {"type": "string"},
+          "parameters": {"type": "object"},
+          "depends_on": {"type": "array", "items": {"type": "string"}},
+          "all_previous": {"type": "boolean"}
+        },
+        "required": ["id", "name", "description", "depends_on"]
+      }
+    }
+  },
+  "required": ["output", "tasks"]
+}`)
+
+// Approver 用于确认规划结果，approved 为 false 时 feedback 作为用户的补充需求重新规划
+type Approver func(c context.Context, ctx *Context, result *Result) (approved bool, feedback string, err error)
+
+// AutoApprover 自动认可规划结果，用于非交互场景
+func AutoApprover(c context.Context, ctx *Context, result *Result) (approved bool, feedback string, err error) {
+	approved = true
+	return
+}
+
 type PlanningAgent struct {
 	CommonAgent
 	cfg       *antagent.Config
-	cli       *openai.Client
+	provider  llm.Provider
 	skills    map[string]*skills.Skill
 	subagents map[string]Agent
+	approver  Approver
+	format    *openai.ChatCompletionResponseFormat
 }
 
-func NewPlanningAgent(cfg *antagent.Config, agentss []Agent, skillss []*skills.Skill) (r *PlanningAgent) {
+func NewPlanningAgent(cfg *antagent.Config, provider llm.Provider, agentss []Agent, skillss []*skills.Skill) (r *PlanningAgent) {
 	r = &PlanningAgent{
 		cfg:       cfg,
+		provider:  provider,
 		skills:    map[string]*skills.Skill{},
 		subagents: map[string]Agent{},
+		format:    planningResponseFormat(cfg.StructuredOutput),
 	}
-	openaiCfg := openai.DefaultConfig(cfg.ApiKey)
-	openaiCfg.BaseURL = cfg.ApiBase
-	r.cli = openai.NewClientWithConfig(openaiCfg)
+	r.approver = r.EditorApprover
 
 	for _, skill := range skillss {
 		r.AddSkill(skill)
@@ -98,9 +147,11 @@ func (this *PlanningAgent) Description() string {
 func (this *PlanningAgent) Clone() Agent {
 	r := &PlanningAgent{
 		cfg:       this.cfg,
-		cli:       this.cli,
+		provider:  this.provider,
 		skills:    map[string]*skills.Skill{},
 		subagents: map[string]Agent{},
+		approver:  this.approver,
+		format:    this.format,
 	}
 
 	for _, skill := range this.skills {
@@ -123,6 +174,11 @@ func (this *PlanningAgent) Clone() Agent {
 	r.AddSystemMessage(fmt.Sprintf(PlanningAgentSystemPrompt, skillsPrompt, subAgentsPrompt))
 	return r
 }
+
+func (this *PlanningAgent) SetApprover(approver Approver) {
+	this.approver = approver
+}
+
 func (this *PlanningAgent) AddSkill(skill *skills.Skill) {
 	this.skills[skill.Meta.Name] = skill
 }
@@ -135,44 +191,83 @@ func (this *PlanningAgent) AddSubAgent(agent Agent) {
 	this.subagents[agent.Name()] = agent
 }
 
+// GetSubAgent 返回名称为 name 的 SubAgent 副本，未注册时返回 nil
 func (this *PlanningAgent) GetSubAgent(name string) Agent {
-	return this.subagents[name].Clone()
+	agent, ok := this.subagents[name]
+	if !ok {
+		return nil
+	}
+	return agent.Clone()
 }
 
-func (this *PlanningAgent) plan() (r *Result, err error) {
-	req := openai.ChatCompletionRequest{
-		Model:       this.cfg.Model,
-		Messages:    this.messages,
-		Temperature: 0,
-	}
-	util.IfDo(this.cfg.Verbose, func() { LogStruct("PlanningAgent LLM Request", req) })
+// plan 请求 LLM 生成规划结果，应答无法解析或未通过校验时携带错误原因重新提问，最多 cfg.PlanMaxRetries 次
+func (this *PlanningAgent) plan(c context.Context, ctx *Context) (r *Result, err error) {
+	base := len(this.messages)
+	for attempt := 0; ; attempt++ {
+		var content string
+		if content, err = this.request(c, ctx); err != nil {
+			return
+		}
 
-	var resp openai.ChatCompletionResponse
-	if resp, err = this.cli.CreateChatCompletion(context.Background(), req); err != nil {
-		err = fmt.Errorf("LLM 请求发生异常: %v", err)
-		return
+		var prompt, reason string
+		r = &Result{}
+		if err = llm.ParseJSON(content, r); err != nil {
+			prompt = fmt.Sprintf(PlanningAgentRepairPromptFormat, err)
+			reason = fmt.Sprintf("规划结果无法解析: %v", err)
+			err = fmt.Errorf("LLM 应答无法解析: %v, %s", err, content)
+		} else if violations := this.Validate(r); len(violations) > 0 {
+			prompt = fmt.Sprintf(PlanningAgentValidatePromptFormat, "- "+strings.Join(violations, "\n- "))
+			err = fmt.Errorf("规划结果校验未通过: %s", strings.Join(violations, "; "))
+			reason = err.Error()
+		} else {
+			// 解析或校验失败的轮次不再保留在上下文中
+			this.messages = append(this.messages[:base], this.messages[len(this.messages)-1])
+			return
+		}
+
+		if attempt >= this.cfg.PlanMaxRetries {
+			return
+		}
+		ctx.Warnf(nil, "‼️ %s，正在要求 LLM 修正...", reason)
+		this.AddUserMessage(prompt)
 	}
-	util.IfDo(this.cfg.Verbose, func() { LogStruct("PlanningAgent LLM Response", resp) })
-	this.AddAssistantMessage(resp.Choices[0].Message.Content)
+}
 
-	content := TrimLLMResp(resp.Choices[0].Message.Content)
+func (this *PlanningAgent) request(c context.Context, ctx *Context) (r string, err error) {
+	req := &llm.Request{
+		Model:          this.cfg.Model,
+		Messages:       this.messages,
+		Temperature:    0,
+		ResponseFormat: this.format,
+	}
 
-	r = &Result{}
-	if err = json.Unmarshal([]byte(content), r); err != nil {
-		err = fmt.Errorf("LLM 应答无法解析: %v, %s", err, content)
+	var resp *llm.Response
+	resp, err = this.chat(c, ctx, nil, this.Name(), this.provider, req, true)
+	if err != nil && req.ResponseFormat != nil && llm.IsBadRequest(err) {
+		// 模型不支持结构化输出，之后的请求均依赖提示词与容错解析
+		ctx.Warnf(nil, "‼️ 模型不支持结构化输出，改为普通输出: %v", err)
+		this.format = nil
+		req.ResponseFormat = nil
+		resp, err = this.chat(c, ctx, nil, this.Name(), this.provider, req, true)
+	}
+	if err != nil {
+		err = fmt.Errorf("LLM 请求发生异常: %v", err)
 		return
 	}
+	this.AddAssistantMessage(resp.Message.Content)
+
+	r = resp.Message.Content
 	return
 }
 
-func (this *PlanningAgent) Execute(ctx *Context, task *Task) (r *Result, err error) {
-	fmt.Printf("🧠 正在规划你的任务...\n")
+func (this *PlanningAgent) Execute(c context.Context, ctx *Context, task *Task) (r *Result, err error) {
+	ctx.Emit(EventPlanStart, nil, ctx.Input)
 	r = &Result{}
-	this.AddUserMessage(ctx.Input)
+	this.AddUserMessage(ctx.Query())
 
 	for {
 		var result *Result
-		if result, err = this.plan(); err != nil {
+		if result, err = this.plan(c, ctx); err != nil {
 			err = fmt.Errorf("任务规划异常: %v", err)
 			return
 		}
@@ -182,23 +277,35 @@ func (this *PlanningAgent) Execute(ctx *Context, task *Task) (r *Result, err err
 			return
 		}
 
-		fmt.Printf("📝 LLM 已经完成任务规划: \n")
-		for idx, task := range result.Tasks {
-			fmt.Printf(" %d. [%s] %s.\n", idx+1, task.Name, task.Description)
-		}
-		fmt.Printf("\n\n❓ 请确认是否认可该方案？认可请回复 继续/y/yes，否则请继续完善你的需求\n")
+		ctx.Emit(EventPlan, nil, result)
 
-		var input string
-		if input, err = antagent.GetInput(); err != nil {
-			err = fmt.Errorf("用户输入获取异常: %v", err)
+		var approved bool
+		var feedback string
+		if approved, feedback, err = this.approver(c, ctx, result); err != nil {
 			return
 		}
-		if exists, _ := util.InSlice(input, []string{"继续", "y", "yes"}); exists {
+		if approved {
 			r = result
 			return
 		}
 
-		this.AddUserMessage(input)
-		fmt.Printf("🔄 正在重新规划你的任务...\n")
+		this.AddUserMessage(feedback)
+		ctx.Emit(EventReplan, nil, &ReplanEvent{Feedback: feedback})
+	}
+}
+
+func planningResponseFormat(mode string) *openai.ChatCompletionResponseFormat {
+	switch mode {
+	case "json_schema":
+		return &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   "plan",
+				Schema: PlanningAgentResultSchema,
+			},
+		}
+	case "json_object":
+		return &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
 	}
+	return nil
 }
@@ -1,5 +1,9 @@
 package agents
 
+import (
+	"context"
+)
+
 type PPTSubAgent struct {
 	CommonAgent
 }
@@ -21,6 +25,6 @@ func (this *PPTSubAgent) Clone() Agent {
 	return r
 }
 
-func (this *PPTSubAgent) Execute(ctx *Context, task *Task) (*Result, error) {
+func (this *PPTSubAgent) Execute(c context.Context, ctx *Context, task *Task) (*Result, error) {
 	return &Result{}, nil
 }
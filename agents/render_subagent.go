@@ -1,8 +1,7 @@
 package agents
 
 import (
-	"fmt"
-
+	"context"
 	markdown "github.com/MichaelMure/go-term-markdown"
 	antagent "github.com/ant-libs-go/ant-agent"
 )
@@ -34,17 +33,14 @@ func (this *RenderSubAgent) Clone() Agent {
 	return r
 }
 
-func (this *RenderSubAgent) Execute(ctx *Context, task *Task) (r *Result, err error) {
-	fmt.Printf("\t 📝 正在渲染 Markdown 内容...\n")
+func (this *RenderSubAgent) Execute(c context.Context, ctx *Context, task *Task) (r *Result, err error) {
+	ctx.Infof(task, "📝 正在渲染 Markdown 内容...")
 	r = &Result{}
 
-	for i := len(ctx.Tasks) - 1; i >= 0; i-- {
-		if ctx.Tasks[i].Name != "ReportSubAgent" {
-			continue
-		}
-		r.Output = string(markdown.Render(ctx.Tasks[i].Output, 80, 6))
+	if report := ctx.Report(); report != "" {
+		r.Output = string(markdown.Render(report, 80, 6))
 	}
 
-	fmt.Printf("\t 💬 渲染完成\n")
+	ctx.Infof(task, "💬 渲染完成")
 	return
 }
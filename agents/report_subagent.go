@@ -6,13 +6,13 @@ import (
 	"strings"
 
 	antagent "github.com/ant-libs-go/ant-agent"
-	"github.com/ant-libs-go/util"
-	openai "github.com/sashabaranov/go-openai"
+	"github.com/ant-libs-go/ant-agent/llm"
 )
 
 const ReportAgentSystemPrompt = `你是一个报告写作助手，负责创建格式良好、清晰且全面的 Markdown 格式报告。
 使用适当的标题、列表和格式使报告易于阅读。
-如果提供的信息包含带有 URL 和描述的图片，请选择最相关的图片，并使用标准 Markdown 图片语法 "![描述](URL)" 将其嵌入报告中。将图片放置在相关文本部分附近。`
+如果提供的信息包含带有 URL 和描述的图片，请选择最相关的图片，并使用标准 Markdown 图片语法 "![描述](URL)" 将其嵌入报告中。将图片放置在相关文本部分附近。
+资料中以 [n] 标注的是来源编号。引用资料中的事实或数据时，在句末使用对应的编号标注，例如 [3] 或 [2, 5]；只能使用资料中出现过的编号，不要编造编号，也不要自行编写参考资料列表，参考资料列表会根据引用自动生成。`
 const ReportAgentUserPromptFormat = `用户的重要指令/请求: %s
 基于以下信息，%s：
 
@@ -20,17 +20,15 @@ const ReportAgentUserPromptFormat = `用户的重要指令/请求: %s
 
 type ReportSubAgent struct {
 	CommonAgent
-	cfg *antagent.Config
-	cli *openai.Client
+	cfg      *antagent.Config
+	provider llm.Provider
 }
 
-func NewReportSubAgent(cfg *antagent.Config) (r *ReportSubAgent) {
+func NewReportSubAgent(cfg *antagent.Config, provider llm.Provider) (r *ReportSubAgent) {
 	r = &ReportSubAgent{
-		cfg: cfg,
+		cfg:      cfg,
+		provider: provider,
 	}
-	openaiCfg := openai.DefaultConfig(cfg.ApiKey)
-	openaiCfg.BaseURL = cfg.ApiBase
-	r.cli = openai.NewClientWithConfig(openaiCfg)
 
 	r.AddSystemMessage(ReportAgentSystemPrompt)
 	return
@@ -46,45 +44,39 @@ func (this *ReportSubAgent) Description() string {
 
 func (this *ReportSubAgent) Clone() Agent {
 	r := &ReportSubAgent{
-		cfg: this.cfg,
-		cli: this.cli,
+		cfg:      this.cfg,
+		provider: this.provider,
 	}
 
 	r.AddSystemMessage(ReportAgentSystemPrompt)
 	return r
 }
 
-func (this *ReportSubAgent) Execute(ctx *Context, task *Task) (r *Result, err error) {
-	fmt.Printf("\t 📝 正在生成报告...\n")
+func (this *ReportSubAgent) Execute(c context.Context, ctx *Context, task *Task) (r *Result, err error) {
+	ctx.Infof(task, "📝 正在生成报告...")
 	r = &Result{}
 
-	references := []string{}
-	for i, t := range ctx.Tasks {
-		if i >= ctx.Offset || len(t.Output) == 0 {
-			continue
-		}
-		references = append(references, fmt.Sprintf("Output from %s task:\n%s", t.Name, t.Output))
-	}
-	this.AddUserMessage(fmt.Sprintf(ReportAgentUserPromptFormat, ctx.Input, task.Description, strings.Join(references, "\n\n")))
+	references := ctx.References(task, this.cfg.AllPrevious)
+	budget := NewTokenBudget(this.cfg, this.provider)
+	references = budget.Fit(c, ctx, task, budget.Available(this.messages, fmt.Sprintf(ReportAgentUserPromptFormat, ctx.Query(), task.Description, "")), references)
+	this.AddUserMessage(fmt.Sprintf(ReportAgentUserPromptFormat, ctx.Query(), task.Description, strings.Join(references, "\n\n")))
 
-	req := openai.ChatCompletionRequest{
+	req := &llm.Request{
 		Model:       this.cfg.Model,
 		Messages:    this.messages,
 		Temperature: 0,
 	}
-	util.IfDo(this.cfg.Verbose, func() { LogStruct("ReportSubAgent LLM Request", req) })
 
-	var resp openai.ChatCompletionResponse
-	if resp, err = this.cli.CreateChatCompletion(context.Background(), req); err != nil {
+	var resp *llm.Response
+	if resp, err = this.chat(c, ctx, task, this.Name(), this.provider, req, true); err != nil {
 		err = fmt.Errorf("LLM 请求发生异常: %v", err)
 		return
 	}
-	util.IfDo(this.cfg.Verbose, func() { LogStruct("ReportSubAgent LLM Response", resp) })
-	this.AddAssistantMessage(resp.Choices[0].Message.Content)
+	this.AddAssistantMessage(resp.Message.Content)
 
-	llmResp := TrimLLMResp(resp.Choices[0].Message.Content)
+	llmResp := TrimLLMResp(resp.Message.Content)
 
-	r.Output = llmResp
-	fmt.Printf("\t 💬 生成报告完成\n")
+	r.Output = ctx.AppendReferences(llmResp)
+	ctx.Infof(task, "💬 生成报告完成")
 	return
 }
@@ -0,0 +1,141 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 差异中变更行前后保留的上下文行数
+const diffContextLines = 2
+
+// ReportVersion 为报告的一个版本，版本 1 为研究生成的原始报告
+type ReportVersion struct {
+	Version     int       `json:"version"`
+	Instruction string    `json:"instruction,omitempty"` // 修订要求，原始报告为空
+	Content     string    `json:"content"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Report 返回当前版本的报告，尚未修订过时返回最后一个 ReportSubAgent 的输出
+func (this *Context) Report() string {
+	if v := this.ReportVersionOf(this.CurrentReport); v != nil {
+		return v.Content
+	}
+	for i := len(this.Tasks) - 1; i >= 0; i-- {
+		if this.Tasks[i].Name == "ReportSubAgent" && this.Tasks[i].Output != "" {
+			return this.Tasks[i].Output
+		}
+	}
+	return ""
+}
+
+// AddReportVersion 记录修订后的报告并将其设为当前版本，首次修订时先将原始报告记为版本 1
+func (this *Context) AddReportVersion(instruction string, content string) (r *ReportVersion) {
+	if len(this.Reports) == 0 {
+		if report := this.Report(); report != "" {
+			this.Reports = append(this.Reports, &ReportVersion{Version: 1, Content: report, CreatedAt: time.Now()})
+		}
+	}
+
+	r = &ReportVersion{
+		Version:     len(this.Reports) + 1,
+		Instruction: instruction,
+		Content:     content,
+		CreatedAt:   time.Now(),
+	}
+	this.Reports = append(this.Reports, r)
+	this.CurrentReport = r.Version
+	return
+}
+
+func (this *Context) ReportVersionOf(version int) *ReportVersion {
+	if version < 1 || version > len(this.Reports) {
+		return nil
+	}
+	return this.Reports[version-1]
+}
+
+// RollbackReport 将当前报告切换为指定版本，之后的修订基于该版本进行
+func (this *Context) RollbackReport(version int) (err error) {
+	if this.ReportVersionOf(version) == nil {
+		err = fmt.Errorf("报告版本 %d 不存在，当前共有 %d 个版本", version, len(this.Reports))
+		return
+	}
+	this.CurrentReport = version
+	return
+}
+
+// ClearReports 清除报告的所有版本，新的研究开始时调用
+func (this *Context) ClearReports() {
+	this.Reports = nil
+	this.CurrentReport = 0
+}
+
+// DiffLines 按行比较 a 与 b，删除的行以 "- " 开头，新增的行以 "+ " 开头，
+// 未变更的行只保留变更附近的上下文
+func DiffLines(a string, b string) string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// lcs[i][j] 为 x[i:] 与 y[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	lines := []line{}
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			lines = append(lines, line{' ', x[i]})
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', x[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', y[j]})
+			j++
+		}
+	}
+
+	// 标记需要输出的行：所有变更行及其上下文
+	keep := make([]bool, len(lines))
+	for idx, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for k := max(0, idx-diffContextLines); k <= min(len(lines)-1, idx+diffContextLines); k++ {
+			keep[k] = true
+		}
+	}
+
+	var sb strings.Builder
+	skipped := false
+	for idx, l := range lines {
+		if !keep[idx] {
+			skipped = true
+			continue
+		}
+		if skipped && sb.Len() > 0 {
+			sb.WriteString("...\n")
+		}
+		skipped = false
+		sb.WriteString(fmt.Sprintf("%c %s\n", l.op, l.text))
+	}
+	return sb.String()
+}
@@ -0,0 +1,96 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/ant-agent/llm"
+)
+
+const ReviseAgentSystemPrompt = `你是一个报告修订助手，负责按照用户的修订要求修改已有的 Markdown 报告。
+只做要求的修改，保留其余内容、结构与格式；需要补充数据时只能使用提供的研究资料。
+报告中的 [n] 为来源编号，请保留原有的引用编号，新增内容只能引用研究资料中出现过的编号；不要编写参考资料列表，它会自动生成。
+直接输出修订后的完整报告，不要添加任何解释。`
+const ReviseAgentUserPromptFormat = `用户的原始请求: %s
+修订要求: %s
+
+当前报告:
+%s
+
+可参考的研究资料:
+%s`
+
+// ReviseAgent 按照修订要求改写当前版本的报告，不重新执行研究
+type ReviseAgent struct {
+	CommonAgent
+	cfg      *antagent.Config
+	provider llm.Provider
+}
+
+func NewReviseAgent(cfg *antagent.Config, provider llm.Provider) (r *ReviseAgent) {
+	r = &ReviseAgent{
+		cfg:      cfg,
+		provider: provider,
+	}
+
+	r.AddSystemMessage(ReviseAgentSystemPrompt)
+	return
+}
+
+func (this *ReviseAgent) Name() string {
+	return "ReviseAgent"
+}
+
+func (this *ReviseAgent) Description() string {
+	return "按照修订要求改写报告"
+}
+
+func (this *ReviseAgent) Clone() Agent {
+	return NewReviseAgent(this.cfg, this.provider)
+}
+
+// Execute 按照 task.Description 中的修订要求改写 ctx.Report()
+func (this *ReviseAgent) Execute(c context.Context, ctx *Context, task *Task) (r *Result, err error) {
+	ctx.Infof(task, "✍️ 正在修订报告...")
+	r = &Result{}
+
+	ctx.RLock()
+	report := StripReferences(ctx.Report())
+	references := []string{}
+	for _, t := range ctx.Tasks {
+		if t.Output == "" || t.Name == "ReportSubAgent" || t.Name == "RenderSubAgent" {
+			continue
+		}
+		references = append(references, fmt.Sprintf("Output from %s task:\n%s", t.Name, t.Output))
+	}
+	ctx.RUnlock()
+
+	if report == "" {
+		err = errors.New("当前会话没有可修订的报告")
+		return
+	}
+
+	budget := NewTokenBudget(this.cfg, this.provider)
+	references = budget.Fit(c, ctx, task, budget.Available(this.messages, fmt.Sprintf(ReviseAgentUserPromptFormat, ctx.Input, task.Description, report, "")), references)
+	this.AddUserMessage(fmt.Sprintf(ReviseAgentUserPromptFormat, ctx.Input, task.Description, report, strings.Join(references, "\n\n")))
+
+	req := &llm.Request{
+		Model:       this.cfg.Model,
+		Messages:    this.messages,
+		Temperature: 0,
+	}
+
+	var resp *llm.Response
+	if resp, err = this.chat(c, ctx, task, this.Name(), this.provider, req, true); err != nil {
+		err = fmt.Errorf("LLM 请求发生异常: %v", err)
+		return
+	}
+	this.AddAssistantMessage(resp.Message.Content)
+
+	r.Output = ctx.AppendReferences(TrimLLMResp(resp.Message.Content))
+	ctx.Infof(task, "💬 报告修订完成")
+	return
+}
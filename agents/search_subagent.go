@@ -1,17 +1,16 @@
 package agents
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/ant-agent/llm"
+	"github.com/ant-libs-go/ant-agent/search"
 	"github.com/ant-libs-go/util"
 	openai "github.com/sashabaranov/go-openai"
 )
@@ -25,20 +24,27 @@ const SearchAgentUserPromptFormat = `用户查询: %s
 如果是，请仅回复 "SUFFICIENT"。
 如果否，请回复一个新的、更精细的搜索查询以查找缺失的信息。不要添加任何其他文本。
 `
+const SearchAgentExpandPromptFormat = `用户查询: %s
+请围绕该查询再生成 %d 个互补的搜索查询，与原查询一起并行检索。可以换一种表述、使用另一种语言（例如中文与英文互译），或拆分出需要分别检索的子问题。
+查询之间以及与原查询之间不要重复。
+仅返回 JSON 字符串数组，例如 ["查询一", "query two"]，不要添加任何其他文本。`
+
+// 单个检索任务并行查询数的上限
+const maxSearchBreadth = 8
 
 type SearchSubAgent struct {
 	CommonAgent
-	cfg *antagent.Config
-	cli *openai.Client
+	cfg      *antagent.Config
+	provider llm.Provider
+	searcher search.Provider
 }
 
-func NewSearchSubAgent(cfg *antagent.Config) (r *SearchSubAgent) {
+func NewSearchSubAgent(cfg *antagent.Config, provider llm.Provider, searcher search.Provider) (r *SearchSubAgent) {
 	r = &SearchSubAgent{
-		cfg: cfg,
+		cfg:      cfg,
+		provider: provider,
+		searcher: searcher,
 	}
-	openaiCfg := openai.DefaultConfig(cfg.ApiKey)
-	openaiCfg.BaseURL = cfg.ApiBase
-	r.cli = openai.NewClientWithConfig(openaiCfg)
 
 	r.AddSystemMessage(SearchAgentSystemPrompt)
 	return
@@ -49,21 +55,19 @@ func (this *SearchSubAgent) Name() string {
 }
 
 func (this *SearchSubAgent) Description() string {
-	return "执行网络搜索以收集信息"
+	return "执行网络搜索以收集信息，可选参数 breadth 指定并行检索的互补查询数"
 }
 
-func (this *SearchSubAgent) Clone() Agent {
-	r := &SearchSubAgent{
-		cfg: this.cfg,
-		cli: this.cli,
-	}
+func (this *SearchSubAgent) RequiredParameters() []string {
+	return []string{"query"}
+}
 
-	r.AddSystemMessage(SearchAgentSystemPrompt)
-	return r
+func (this *SearchSubAgent) Clone() Agent {
+	return NewSearchSubAgent(this.cfg, this.provider, this.searcher)
 }
 
-func (this *SearchSubAgent) Execute(ctx *Context, task *Task) (r *Result, err error) {
-	fmt.Printf("\t 🔍 正在从互联网检索...\n")
+func (this *SearchSubAgent) Execute(c context.Context, ctx *Context, task *Task) (r *Result, err error) {
+	ctx.Infof(task, "🔍 正在从互联网检索...")
 	r = &Result{}
 
 	query, ok := task.Parameters["query"].(string)
@@ -71,210 +75,173 @@ func (this *SearchSubAgent) Execute(ctx *Context, task *Task) (r *Result, err er
 		query = task.Description
 	}
 
-	var content string
+	breadth := min(task.IntParameter("breadth", this.cfg.SearchBreadth), maxSearchBreadth)
+	queries := []string{query}
+	if breadth > 1 {
+		queries = append(queries, this.expand(c, ctx, task, query, breadth-1)...)
+	}
 
 	// 检索到的信息进行反思，最多反思 3 次
+	deduper := search.NewDeduper()
 	for i := 0; i < 3; i++ {
-		if content, err = this.SearchForTavily(query); err != nil {
+		var results []*search.Result
+		var images []string
+		if results, images, err = this.fanout(c, ctx, task, queries, deduper); err != nil {
 			err = fmt.Errorf("网络检索发生异常: %v", err)
 			return
 		}
+		content := FormatSources(ctx.AddSources(SourcesOf(results)))
+		if len(images) > 0 {
+			content += "\nRelevant Images:\n"
+			for _, imgURL := range images {
+				content += fmt.Sprintf("- Image URL: %s\n", imgURL)
+			}
+			content += "\n"
+		}
 
 		util.IfDo(r.Output != "", func() { r.Output += "\n\n--- Additional Search Results ---\n" })
 		r.Output += content
 		this.AddUserMessage(fmt.Sprintf(SearchAgentUserPromptFormat, query, content))
 
-		req := openai.ChatCompletionRequest{
+		req := &llm.Request{
 			Model:       this.cfg.Model,
 			Messages:    this.messages,
 			Temperature: 0,
 		}
-		util.IfDo(this.cfg.Verbose, func() { LogStruct("SearchSubAgent LLM Request", req) })
 
-		var resp openai.ChatCompletionResponse
-		if resp, err = this.cli.CreateChatCompletion(context.Background(), req); err != nil {
+		var resp *llm.Response
+		if resp, err = this.chat(c, ctx, task, this.Name(), this.provider, req, false); err != nil {
 			err = fmt.Errorf("LLM 请求发生异常: %v", err)
 			return
 		}
-		util.IfDo(this.cfg.Verbose, func() { LogStruct("SearchSubAgent LLM Response", resp) })
-		this.AddAssistantMessage(resp.Choices[0].Message.Content)
+		this.AddAssistantMessage(resp.Message.Content)
 
-		llmResp := TrimLLMResp(resp.Choices[0].Message.Content)
+		llmResp := TrimLLMResp(resp.Message.Content)
 		if strings.Contains(strings.ToUpper(llmResp), "SUFFICIENT") {
-			fmt.Printf("\t 💬 检索完成，LLM 判定信息足以回答用户的查询\n")
+			ctx.Infof(task, "💬 检索完成，LLM 判定信息足以回答用户的查询")
 			break
 		}
 
 		query = strings.TrimSpace(llmResp)
-		fmt.Printf("\t 🔄 正在补充检索: %s\n", query)
+		queries = []string{query}
+		ctx.Infof(task, "🔄 正在补充检索: %s", query)
+		// 补充检索同样并行检索互补查询
+		if breadth > 1 {
+			queries = append(queries, this.expand(c, ctx, task, query, breadth-1)...)
+		}
 	}
 
 	return
 }
 
-func (this *SearchSubAgent) SearchForTavily(query string) (r string, err error) {
-	b, _ := json.Marshal(map[string]interface{}{
-		"query":          query,
-		"search_depth":   "basic",
-		"max_results":    20,
-		"include_images": true,
-	})
-
-	var req *http.Request
-	if req, err = http.NewRequest("POST", "https://api.tavily.com/search", bytes.NewBuffer(b)); err != nil {
-		err = fmt.Errorf("failed to create request: %v", err)
-		return
+// expand 让 LLM 围绕 query 生成 n 个互补的查询，失败时只使用原查询
+func (this *SearchSubAgent) expand(c context.Context, ctx *Context, task *Task, query string, n int) (r []string) {
+	req := &llm.Request{
+		Model: this.cfg.Model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: SearchAgentSystemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: fmt.Sprintf(SearchAgentExpandPromptFormat, query, n)},
+		},
+		Temperature: 0,
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", this.cfg.TavilyApiKey))
 
-	var resp *http.Response
-	if resp, err = (&http.Client{Timeout: 30 * time.Second}).Do(req); err != nil {
-		err = fmt.Errorf("failed to perform Tavily search: %v", err)
+	resp, err := this.chat(c, ctx, task, this.Name(), this.provider, req, false)
+	if err != nil {
+		ctx.Warnf(task, "‼️ 生成互补查询失败，将只检索原查询: %v", err)
 		return
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		err = fmt.Errorf("Tavily API returned status %d: %s", resp.StatusCode, string(body))
+	var queries []string
+	if err = llm.ParseJSON(TrimLLMResp(resp.Message.Content), &queries); err != nil {
+		ctx.Warnf(task, "‼️ 互补查询解析失败，将只检索原查询: %v", err)
 		return
 	}
 
-	var result struct {
-		Results []struct {
-			Title   string `json:"title"`
-			URL     string `json:"url"`
-			Content string `json:"content"`
-		} `json:"results"`
-		Images []string `json:"images"`
-	}
-
-	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		err = fmt.Errorf("failed to decode Tavily response: %v", err)
-		return
-	}
-
-	var sb bytes.Buffer
-	for _, item := range result.Results {
-		sb.WriteString(fmt.Sprintf("Title: %s\nURL: %s\nContent: %s\n\n", item.Title, item.URL, item.Content))
-	}
-
-	if len(result.Images) > 0 {
-		sb.WriteString("\nRelevant Images:\n")
-		for _, imgURL := range result.Images {
-			sb.WriteString(fmt.Sprintf("- Image URL: %s\n", imgURL))
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(query)): true}
+	for _, q := range queries {
+		key := strings.ToLower(strings.TrimSpace(q))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		r = append(r, strings.TrimSpace(q))
+		if len(r) == n {
+			break
 		}
-		sb.WriteString("\n")
-	}
-
-	if sb.Len() == 0 {
-		err = fmt.Errorf("no results found")
-		return
 	}
-
-	r = sb.String()
-	util.IfDo(this.cfg.Verbose, func() { LogStruct("SearchSubAgent SearchForTavily Result", r) })
+	util.IfDo(len(r) > 0, func() {
+		ctx.Infof(task, "🧭 并行检索 %d 个查询: %s", len(r)+1, strings.Join(append([]string{query}, r...), " | "))
+	})
 	return
 }
 
-func (this *SearchSubAgent) SearchForDuckDuckGo(query string) (r string, err error) {
-	var req *http.Request
-	if req, err = http.NewRequest("GET", fmt.Sprintf("https://api.duckduckgo.com/?format=json&q=%s", url.QueryEscape(query)), nil); err != nil {
-		err = fmt.Errorf("failed to create request: %v", err)
-		return
-	}
-
-	var resp *http.Response
-	if resp, err = (&http.Client{Timeout: 10 * time.Second}).Do(req); err != nil {
-		err = fmt.Errorf("failed to perform DuckDuckGo search: %v", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		err = fmt.Errorf("DuckDuckGo API returned status %d: %s", resp.StatusCode, string(body))
-		return
-	}
-
-	var result struct {
-		AbstractText  string `json:"AbstractText"`
-		AbstractURL   string `json:"AbstractURL"`
-		RelatedTopics []struct {
-			Text string `json:"Text"`
-			URL  string `json:"URL"`
-		} `json:"RelatedTopics"`
-	}
-
-	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		err = fmt.Errorf("failed to decode DuckDuckGo response: %v", err)
-		return
-	}
-
-	if result.AbstractText != "" {
-		r = fmt.Sprintf("%s (Source: %s)", result.AbstractText, result.AbstractURL)
-		util.IfDo(this.cfg.Verbose, func() { LogStruct("SearchSubAgent SearchForDuckDuckGo Result", r) })
-		return
-	}
-	// 如果没有摘要，则回退到相关主题
-	if len(result.RelatedTopics) > 0 {
-		var topics []string
-		for _, topic := range result.RelatedTopics {
-			topics = append(topics, topic.Text)
+// fanout 并发检索 queries，各查询轮流优先使用不同的检索服务，按查询顺序合并结果并通过 deduper 去除此前已检索到的重复内容，
+// 部分查询失败时只输出警告，全部失败时返回错误
+func (this *SearchSubAgent) fanout(c context.Context, ctx *Context, task *Task, queries []string, deduper *search.Deduper) (r []*search.Result, images []string, err error) {
+	resps := make([]*search.Response, len(queries))
+	errs := make([]error, len(queries))
+
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resps[i], errs[i] = this.search(c, ctx, task, search.Spread(this.searcher, i), query)
+		}()
+	}
+	wg.Wait()
+
+	total, seenImages := 0, map[string]bool{}
+	for i, resp := range resps {
+		if errs[i] != nil {
+			err = errs[i]
+			util.IfDo(len(queries) > 1, func() { ctx.Warnf(task, "‼️ 查询[%s]检索失败: %v", queries[i], errs[i]) })
+			continue
+		}
+		total += len(resp.Results)
+		r = append(r, deduper.Add(resp.Results)...)
+		for _, img := range resp.Images {
+			if !seenImages[img] {
+				seenImages[img] = true
+				images = append(images, img)
+			}
 		}
-		r = fmt.Sprintf("No direct abstract found. Related topics: %s", strings.Join(topics, "; "))
-		util.IfDo(this.cfg.Verbose, func() { LogStruct("SearchSubAgent SearchForDuckDuckGo Result", r) })
-		return
 	}
 
-	err = fmt.Errorf("no results found")
+	if total > 0 || len(images) > 0 {
+		err = nil
+	}
+	util.IfDo(err == nil && len(queries) > 1, func() { ctx.Infof(task, "🧹 合并 %d 条检索结果，去重后保留 %d 条", total, len(r)) })
 	return
 }
 
-func (this *SearchSubAgent) SearchForWikipedia(query string) (r string, err error) {
-	var req *http.Request
-	if req, err = http.NewRequest("GET", fmt.Sprintf("https://en.wikipedia.org/w/api.php?action=query&format=json&prop=extracts&exintro=&explaintext=&redirects=1&titles=%s", url.QueryEscape(query)), nil); err != nil {
-		err = fmt.Errorf("failed to create request: %v", err)
+// search 通过 searcher 查询 query，回退链中失败的服务以警告的形式输出
+func (this *SearchSubAgent) search(c context.Context, ctx *Context, task *Task, searcher search.Provider, query string) (r *search.Response, err error) {
+	if searcher == nil {
+		err = errors.New("未配置可用的检索服务")
 		return
 	}
-
-	var resp *http.Response
-	if resp, err = (&http.Client{Timeout: 10 * time.Second}).Do(req); err != nil {
-		err = fmt.Errorf("failed to perform Wikipedia search: %v", err)
-		return
+	r, err = searcher.Search(c, query)
+	if r != nil {
+		for _, f := range r.Failures {
+			ctx.Warnf(task, "‼️ 检索服务不可用，已切换至 %s: %v", r.Provider, f)
+		}
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		err = fmt.Errorf("Wikipedia API returned status %d: %s", resp.StatusCode, string(body))
+	if err != nil {
 		return
 	}
-	var result struct {
-		Query struct {
-			Pages map[string]struct {
-				Extract string `json:"extract"`
-			} `json:"pages"`
-		} `json:"query"`
-	}
-
-	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		err = fmt.Errorf("failed to decode Wikipedia response: %v", err)
-		return
+	util.IfDo(r.Cached, func() { ctx.Debugf(task, "🗄️ 查询[%s]命中 %s 的缓存", query, r.Provider) })
+	ctx.Debugf(task, "🔍 查询[%s]由 %s 返回 %d 条结果", query, r.Provider, len(r.Results))
+	for _, item := range r.Results {
+		ctx.Debugf(task, "  - %s %s", item.Title, item.URL)
 	}
+	return
+}
 
-	for _, page := range result.Query.Pages {
-		if page.Extract != "" {
-			// 清理一些常见的维基百科 API 伪影
-			extract := strings.ReplaceAll(page.Extract, "(listen)", "")
-			extract = strings.TrimSpace(extract)
-			r = extract
-			util.IfDo(this.cfg.Verbose, func() { LogStruct("SearchSubAgent SearchForWikipedia Result", r) })
-			return
-		}
+// SourcesOf 将检索结果转换为待登记的来源
+func SourcesOf(results []*search.Result) (r []*Source) {
+	now := time.Now()
+	for _, item := range results {
+		r = append(r, &Source{Title: item.Title, URL: item.URL, Snippet: item.Content, RetrievedAt: now})
 	}
-
-	err = fmt.Errorf("No relevant Wikipedia entry found.")
 	return
 }
@@ -0,0 +1,126 @@
+package agents
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NewSessionId 返回以时间开头、便于按时间排序的会话 id，随机后缀保证同一秒内创建的会话不会相互覆盖
+func NewSessionId() string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// SaveSession 将 ctx 以 JSON 写入 dir/<id>.json，先写临时文件再重命名，避免中途退出导致文件损坏
+func SaveSession(dir string, ctx *Context) (err error) {
+	ctx.Lock()
+	if ctx.Id == "" {
+		ctx.Id = NewSessionId()
+	}
+	ctx.UpdatedAt = time.Now()
+	ctx.Unlock()
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		err = fmt.Errorf("failed to create session dir: %w", err)
+		return
+	}
+
+	var b []byte
+	if b, err = json.MarshalIndent(ctx, "", "  "); err != nil {
+		err = fmt.Errorf("failed to marshal session: %w", err)
+		return
+	}
+
+	path := filepath.Join(dir, ctx.Id+".json")
+	if err = os.WriteFile(path+".tmp", b, 0644); err != nil {
+		err = fmt.Errorf("failed to write session: %w", err)
+		return
+	}
+	if err = os.Rename(path+".tmp", path); err != nil {
+		err = fmt.Errorf("failed to write session: %w", err)
+		return
+	}
+	return
+}
+
+// LoadSession 读取 dir/<id>.json，中断时仍在运行的任务会被重置为待执行。
+// McpClient 等不可序列化的字段需要调用方重新设置
+func LoadSession(dir string, id string) (r *Context, err error) {
+	var b []byte
+	if b, err = os.ReadFile(filepath.Join(dir, id+".json")); err != nil {
+		err = fmt.Errorf("failed to read session: %w", err)
+		return
+	}
+
+	r = &Context{}
+	if err = json.Unmarshal(b, r); err != nil {
+		err = fmt.Errorf("failed to parse session: %w", err)
+		return
+	}
+
+	for _, t := range r.Tasks {
+		if t.Status == TaskStatusRunning {
+			t.Status = TaskStatusPending
+		}
+	}
+	return
+}
+
+// RetryFailed 将失败的任务，以及在缺少其输出的情况下已经执行完成的任务重置为待执行，返回被重置的任务数。
+// 调度器将失败的任务视为已结束，继续执行会话前需要调用，all 对应 Config.AllPrevious
+func (this *Context) RetryFailed(all bool) (n int) {
+	this.Lock()
+	defer this.Unlock()
+
+	reset := map[string]bool{}
+	first := len(this.Tasks) // 第一个被重置的任务的位置，读取全部前序输出的任务在其之后时同样需要重置
+	for changed := true; changed; {
+		changed = false
+		for i, t := range this.Tasks {
+			if t.Status != TaskStatusFailed && t.Status != TaskStatusDone {
+				continue
+			}
+			stale := t.Status == TaskStatusFailed || ((all || t.AllPrevious) && i > first)
+			for _, dep := range t.DependsOn {
+				stale = stale || reset[dep]
+			}
+			if !stale {
+				continue
+			}
+			t.Status, t.Output = TaskStatusPending, ""
+			reset[t.Id], first, changed = true, min(first, i), true
+			n++
+		}
+	}
+	return
+}
+
+// ListSessions 返回 dir 下所有会话的 id，按时间倒序
+func ListSessions(dir string) (r []string, err error) {
+	var entries []os.DirEntry
+	if entries, err = os.ReadDir(dir); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+			return
+		}
+		err = fmt.Errorf("failed to read session dir: %w", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		r = append(r, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(r)))
+	return
+}
@@ -8,9 +8,9 @@ import (
 	"strings"
 
 	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/ant-agent/llm"
 	"github.com/ant-libs-go/ant-agent/skills"
 	"github.com/ant-libs-go/util"
-	openai "github.com/sashabaranov/go-openai"
 )
 
 const SkillSubAgentSystemPrompt = `%s
@@ -24,19 +24,17 @@ const SkillSubAgentUserPromptFormat = `用户的重要指令/请求: %s
 
 type SkillSubAgent struct {
 	CommonAgent
-	skill *skills.Skill
-	cfg   *antagent.Config
-	cli   *openai.Client
+	skill    *skills.Skill
+	cfg      *antagent.Config
+	provider llm.Provider
 }
 
-func NewSkillSubAgent(cfg *antagent.Config, skill *skills.Skill) (r *SkillSubAgent) {
+func NewSkillSubAgent(cfg *antagent.Config, provider llm.Provider, skill *skills.Skill) (r *SkillSubAgent) {
 	r = &SkillSubAgent{
-		cfg:   cfg,
-		skill: skill,
+		cfg:      cfg,
+		provider: provider,
+		skill:    skill,
 	}
-	openaicfg := openai.DefaultConfig(cfg.ApiKey)
-	openaicfg.BaseURL = cfg.ApiBase
-	r.cli = openai.NewClientWithConfig(openaicfg)
 
 	r.AddSystemMessage(fmt.Sprintf(SkillSubAgentSystemPrompt, skill.Body, skill.Path))
 	return
@@ -54,47 +52,37 @@ func (this *SkillSubAgent) Clone() Agent {
 	return nil
 }
 
-func (this *SkillSubAgent) Execute(ctx *Context, task *Task) (r *Result, err error) {
-	fmt.Printf("\t 🔬 正在调用 skill[%s]...\n", this.skill.Meta.Name)
+func (this *SkillSubAgent) Execute(c context.Context, ctx *Context, task *Task) (r *Result, err error) {
+	ctx.Infof(task, "🔬 正在调用 skill[%s]...", this.skill.Meta.Name)
 	r = &Result{}
 
-	references := []string{}
-	for i, t := range ctx.Tasks {
-		if i >= ctx.Offset || len(t.Output) == 0 {
-			continue
-		}
-		references = append(references, fmt.Sprintf("Output from %s task:\n%s", t.Name, t.Output))
-	}
-	this.AddUserMessage(fmt.Sprintf(SkillSubAgentUserPromptFormat, ctx.Input, task.Description, strings.Join(references, "\n\n")))
+	references := ctx.References(task, this.cfg.AllPrevious)
+	budget := NewTokenBudget(this.cfg, this.provider)
+	references = budget.Fit(c, ctx, task, budget.Available(this.messages, fmt.Sprintf(SkillSubAgentUserPromptFormat, ctx.Query(), task.Description, "")), references)
+	this.AddUserMessage(fmt.Sprintf(SkillSubAgentUserPromptFormat, ctx.Query(), task.Description, strings.Join(references, "\n\n")))
 
 	for i := 0; i < 10; i++ {
-		req := openai.ChatCompletionRequest{
+		req := &llm.Request{
 			Model:       this.cfg.Model,
 			Messages:    this.messages,
 			Temperature: 0,
-			Tools:       ctx.McpClient.GetTools(),
+			Tools:       ctx.McpClient.GetTools(c),
 		}
-		util.IfDo(this.cfg.Verbose, func() { LogStruct("SkillSubAgent LLM Request", req) })
 
-		var resp openai.ChatCompletionResponse
-		if resp, err = this.cli.CreateChatCompletion(context.Background(), req); err != nil {
+		var resp *llm.Response
+		if resp, err = this.chat(c, ctx, task, this.Name(), this.provider, req, false); err != nil {
 			err = fmt.Errorf("LLM 请求发生异常: %v", err)
 			return
 		}
-		util.IfDo(this.cfg.Verbose, func() { LogStruct("SkillSubAgent LLM Response", resp) })
-		//this.AddAssistantMessage(resp.Choices[0].Message.Content)
-		this.messages = append(this.messages, resp.Choices[0].Message)
+		//this.AddAssistantMessage(resp.Message.Content)
+		this.messages = append(this.messages, resp.Message)
 
-		if len(resp.Choices[0].Message.ToolCalls) == 0 {
-			r.Output = TrimLLMResp(resp.Choices[0].Message.Content)
+		if len(resp.Message.ToolCalls) == 0 {
+			r.Output = TrimLLMResp(resp.Message.Content)
 			return
 		}
 
-		for _, toolCall := range resp.Choices[0].Message.ToolCalls {
-			util.IfDo(this.cfg.Verbose, func() {
-				fmt.Printf("SkillSubAgent ToolCall[%s]: %s\n", toolCall.Function.Name, toolCall.Function.Arguments)
-			})
-
+		for _, toolCall := range resp.Message.ToolCalls {
 			var args map[string]interface{}
 			if err = json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
 				err = fmt.Errorf("调用 tool[%s] 参数解析失败: %v", toolCall.Function.Name, err)
@@ -102,7 +90,7 @@ func (this *SkillSubAgent) Execute(ctx *Context, task *Task) (r *Result, err err
 
 			var toolResp interface{}
 			if err == nil {
-				if toolResp, err = ctx.McpClient.CallTool(context.Background(), toolCall.Function.Name, args); err != nil {
+				if toolResp, err = ctx.McpClient.CallTool(c, toolCall.Function.Name, args); err != nil {
 					err = fmt.Errorf("调用 tool[%s] 失败: %v", toolCall.Function.Name, err)
 				}
 			}
@@ -112,6 +100,11 @@ func (this *SkillSubAgent) Execute(ctx *Context, task *Task) (r *Result, err err
 			if err != nil {
 				msg = err.Error()
 			}
+			ctx.Emit(EventToolCall, task, &ToolCallEvent{
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
+				Error:     util.If(err != nil, msg, "").(string),
+			})
 
 			this.AddToolMessage(toolCall.ID, msg)
 		}
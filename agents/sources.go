@@ -0,0 +1,170 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ant-libs-go/util"
+)
+
+const referencesHeading = "## 参考资料"
+
+var (
+	citationRegexp = regexp.MustCompile(`\[(\d+(?:\s*[,，]\s*\d+)*)\]`)
+	// 报告末尾的参考资料章节，可能由模型自行编写
+	referencesRegexp = regexp.MustCompile(`(?mi)^#{1,3}\s*(参考资料|参考文献|参考来源|引用来源|references|sources)\s*$`)
+	headingRegexp    = regexp.MustCompile(`(?m)^#{1,6}\s`)
+)
+
+// Source 为一条检索到的来源，Id 在会话内稳定，供报告中的 [n] 引用
+type Source struct {
+	Id          int       `json:"id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Snippet     string    `json:"snippet"`
+	RetrievedAt time.Time `json:"retrieved_at"`
+	// 以下字段在下载网页全文后填充
+	Author      string `json:"author,omitempty"`
+	PublishedAt string `json:"published_at,omitempty"`
+	Content     string `json:"content,omitempty"`
+}
+
+// Sources 为会话内所有来源的登记表，被并发执行的任务共享
+type Sources struct {
+	mu    sync.Mutex
+	items []*Source
+}
+
+func (this *Sources) MarshalJSON() ([]byte, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return json.Marshal(this.items)
+}
+
+func (this *Sources) UnmarshalJSON(b []byte) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return json.Unmarshal(b, &this.items)
+}
+
+// Add 为 items 分配编号并登记，URL 已登记过的来源沿用原有编号并补充下载到的正文等信息，返回 items 本身
+func (this *Sources) Add(items []*Source) []*Source {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	for _, item := range items {
+		if item.RetrievedAt.IsZero() {
+			item.RetrievedAt = time.Now()
+		}
+		if existing := this.find(item.URL); existing != nil {
+			item.Id = existing.Id
+			if item.Content != "" {
+				existing.Content, existing.Author, existing.PublishedAt = item.Content, item.Author, item.PublishedAt
+				existing.Title = util.If(item.Title != "", item.Title, existing.Title).(string)
+			}
+			continue
+		}
+		cp := *item
+		cp.Id = len(this.items) + 1
+		item.Id = cp.Id
+		this.items = append(this.items, &cp)
+	}
+	return items
+}
+
+func (this *Sources) find(url string) *Source {
+	if url == "" {
+		return nil
+	}
+	for _, s := range this.items {
+		if s.URL == url {
+			return s
+		}
+	}
+	return nil
+}
+
+// Get 返回编号为 id 的来源的拷贝，登记表中的来源会被并发的 Add 修改，不能直接交给调用方读取
+func (this *Sources) Get(id int) *Source {
+	if this == nil {
+		return nil
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if id < 1 || id > len(this.items) {
+		return nil
+	}
+	cp := *this.items[id-1]
+	return &cp
+}
+
+// Cited 按首次出现的顺序返回 text 中以 [n] 或 [n, m] 引用的已登记来源的拷贝
+func (this *Sources) Cited(text string) (r []*Source) {
+	seen := map[int]bool{}
+	for _, m := range citationRegexp.FindAllStringSubmatch(text, -1) {
+		for _, v := range strings.FieldsFunc(m[1], func(r rune) bool { return r == ',' || r == '，' || r == ' ' }) {
+			id, _ := strconv.Atoi(v)
+			if s := this.Get(id); s != nil && !seen[id] {
+				seen[id] = true
+				r = append(r, s)
+			}
+		}
+	}
+	return
+}
+
+// AddSources 将检索到的来源登记到会话中
+func (this *Context) AddSources(items []*Source) []*Source {
+	if this.Sources == nil {
+		this.Sources = &Sources{}
+	}
+	return this.Sources.Add(items)
+}
+
+// AppendReferences 移除 report 末尾已有的参考资料章节，并根据其中引用的来源重新生成
+func (this *Context) AppendReferences(report string) string {
+	report = StripReferences(report)
+
+	cited := this.Sources.Cited(report)
+	if len(cited) == 0 {
+		return report
+	}
+
+	var b strings.Builder
+	b.WriteString(report + "\n\n" + referencesHeading + "\n\n")
+	for _, s := range cited {
+		b.WriteString(fmt.Sprintf("- [%d] %s. %s (检索于 %s)\n", s.Id, s.Title, s.URL, s.RetrievedAt.Format("2006-01-02")))
+	}
+	return b.String()
+}
+
+// StripReferences 移除报告末尾的参考资料章节，其后还有其他章节时不做处理
+func StripReferences(report string) string {
+	locs := referencesRegexp.FindAllStringIndex(report, -1)
+	if len(locs) == 0 {
+		return report
+	}
+	loc := locs[len(locs)-1]
+	if headingRegexp.MatchString(report[loc[1]:]) {
+		return report
+	}
+	return strings.TrimRight(report[:loc[0]], "\n ")
+}
+
+// FormatSources 将来源格式化为带编号的参考资料文本，已下载全文的来源输出正文，否则输出摘要
+func FormatSources(sources []*Source) string {
+	var b strings.Builder
+	for _, s := range sources {
+		b.WriteString(fmt.Sprintf("[%d] Title: %s\nURL: %s\n", s.Id, s.Title, s.URL))
+		util.IfDo(s.Author != "", func() { b.WriteString(fmt.Sprintf("Author: %s\n", s.Author)) })
+		util.IfDo(s.PublishedAt != "", func() { b.WriteString(fmt.Sprintf("Published: %s\n", s.PublishedAt)) })
+		b.WriteString(fmt.Sprintf("Content: %s\n\n", util.If(s.Content != "", s.Content, s.Snippet)))
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+package agents
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ant-libs-go/ant-agent/llm"
+)
+
+type Usage struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+func (this *Usage) Add(u *Usage) {
+	this.Calls += u.Calls
+	this.PromptTokens += u.PromptTokens
+	this.CompletionTokens += u.CompletionTokens
+	this.Cost += u.Cost
+}
+
+func (this *Usage) String() string {
+	return fmt.Sprintf("%d 次调用，输入 %d tokens，输出 %d tokens，预估费用 %.4f",
+		this.Calls, this.PromptTokens, this.CompletionTokens, this.Cost)
+}
+
+// UsageStats 汇总一次研究中的 token 用量，Agents 按 Agent 类型分组
+type UsageStats struct {
+	Total  Usage             `json:"total"`
+	Agents map[string]*Usage `json:"agents"`
+}
+
+func NewUsageStats() *UsageStats {
+	return &UsageStats{Agents: map[string]*Usage{}}
+}
+
+func (this *UsageStats) Add(agent string, u *Usage) {
+	this.Total.Add(u)
+	if _, ok := this.Agents[agent]; !ok {
+		this.Agents[agent] = &Usage{}
+	}
+	this.Agents[agent].Add(u)
+}
+
+func (this *UsageStats) Merge(o *UsageStats) {
+	for agent, u := range o.Agents {
+		this.Add(agent, u)
+	}
+}
+
+func (this *UsageStats) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("  合计: %s\n", this.Total.String()))
+
+	agents := make([]string, 0, len(this.Agents))
+	for agent := range this.Agents {
+		agents = append(agents, agent)
+	}
+	sort.Strings(agents)
+	for _, agent := range agents {
+		sb.WriteString(fmt.Sprintf("  %s: %s\n", agent, this.Agents[agent].String()))
+	}
+	return sb.String()
+}
+
+// AddUsage 记录一次 LLM 调用的用量，可能被并发调用
+func (this *Context) AddUsage(agent string, u *Usage) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.Usage == nil {
+		this.Usage = NewUsageStats()
+	}
+	this.Usage.Add(agent, u)
+}
+
+// addLLMUsage 按 agent 记录一次 LLM 调用的 token 用量与费用
+func (this *Context) addLLMUsage(agent string, req *llm.Request, resp *llm.Response) {
+	this.AddUsage(agent, &Usage{
+		Calls:            1,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		Cost:             llm.Cost(req.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens),
+	})
+}
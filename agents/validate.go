@@ -0,0 +1,197 @@
+package agents
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ant-libs-go/util"
+)
+
+// Validate 检查规划结果是否可以执行，返回的问题描述会反馈给 LLM 用于修正计划：
+// 任务名称必须为已注册的 Skill 或 SubAgent、必需参数齐全、依赖的任务存在且没有循环依赖、
+// RenderSubAgent 直接或间接依赖 ReportSubAgent 且任务数量不超过 cfg.MaxPlanTasks
+func (this *PlanningAgent) Validate(result *Result) (r []string) {
+	tasks := result.Tasks
+	if len(tasks) == 0 {
+		return
+	}
+
+	if this.cfg.MaxPlanTasks > 0 && len(tasks) > this.cfg.MaxPlanTasks {
+		r = append(r, fmt.Sprintf("计划包含 %d 个任务，超过了上限 %d 个，请合并或精简任务", len(tasks), this.cfg.MaxPlanTasks))
+	}
+
+	ids := map[string]bool{}
+	for _, t := range tasks {
+		if t.Id == "" {
+			continue
+		}
+		if ids[t.Id] {
+			r = append(r, fmt.Sprintf("任务 id %q 重复，每个任务的 id 必须唯一", t.Id))
+		}
+		ids[t.Id] = true
+	}
+
+	for idx, t := range tasks {
+		label := fmt.Sprintf("第 %d 个任务[%s]", idx+1, t.Name)
+		if _, ok := this.skills[t.Name]; !ok && this.subagents[t.Name] == nil {
+			r = append(r, fmt.Sprintf("%s 不是可用的 Skill 或 SubAgent，只能使用: %s", label, strings.Join(this.names(), ", ")))
+		}
+
+		if agent, ok := this.subagents[t.Name].(ParameterizedAgent); ok {
+			for _, name := range agent.RequiredParameters() {
+				if v, ok := t.Parameters[name]; !ok || v == nil || v == "" {
+					r = append(r, fmt.Sprintf("%s 缺少必需的参数 parameters.%s", label, name))
+				}
+			}
+		}
+
+		for _, dep := range t.DependsOn {
+			if dep == t.Id {
+				r = append(r, fmt.Sprintf("%s 不能依赖自身", label))
+			} else if !ids[dep] {
+				r = append(r, fmt.Sprintf("%s 依赖的任务 %q 不存在", label, dep))
+			}
+		}
+	}
+
+	deps := dependencies(tasks)
+	r = append(r, validateCycles(tasks, deps)...)
+	r = append(r, this.validateRender(tasks, deps)...)
+	return
+}
+
+// dependencies 返回每个任务实际依赖的任务下标。与 Executor.normalize 一致，未声明 depends_on 的任务依赖前一个任务，
+// 不存在的依赖与对自身的依赖被忽略
+func dependencies(tasks []*Task) (r [][]int) {
+	index := map[string]int{}
+	for idx, t := range tasks {
+		if _, ok := index[t.Id]; t.Id != "" && !ok {
+			index[t.Id] = idx
+		}
+	}
+
+	r = make([][]int, len(tasks))
+	for idx, t := range tasks {
+		if t.DependsOn == nil {
+			util.IfDo(idx > 0, func() { r[idx] = []int{idx - 1} })
+			continue
+		}
+		for _, dep := range t.DependsOn {
+			if j, ok := index[dep]; ok && j != idx {
+				r[idx] = append(r[idx], j)
+			}
+		}
+	}
+	return
+}
+
+// validateCycles 检查任务之间的循环依赖，存在循环依赖的任务永远无法被调度执行
+func validateCycles(tasks []*Task, deps [][]int) (r []string) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(tasks))
+	path := []int{}
+
+	var visit func(idx int)
+	visit = func(idx int) {
+		state[idx] = visiting
+		path = append(path, idx)
+		for _, dep := range deps[idx] {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				// path 中从 dep 开始的部分构成一个环
+				cycle := []string{}
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == dep {
+						for _, j := range append(path[i:], dep) {
+							cycle = append(cycle, fmt.Sprintf("%s[%s]", tasks[j].Id, tasks[j].Name))
+						}
+						break
+					}
+				}
+				r = append(r, fmt.Sprintf("任务之间存在循环依赖（箭头表示依赖）: %s，请调整 depends_on", strings.Join(cycle, " → ")))
+			}
+		}
+		path = path[:len(path)-1]
+		state[idx] = visited
+	}
+	for idx := range tasks {
+		if state[idx] == unvisited {
+			visit(idx)
+		}
+	}
+	return
+}
+
+// validateRender 要求每个 RenderSubAgent 直接或间接依赖 ReportSubAgent，任务按依赖关系而不是列表顺序调度，
+// 不依赖报告的渲染任务会与报告并发执行。同时要求最终的 ReportSubAgent（没有其他 ReportSubAgent 依赖它）被某个 RenderSubAgent 依赖
+func (this *PlanningAgent) validateRender(tasks []*Task, deps [][]int) (r []string) {
+	report, render := "ReportSubAgent", "RenderSubAgent"
+	if this.subagents[report] == nil || this.subagents[render] == nil {
+		return
+	}
+
+	ancestors := make([]map[int]bool, len(tasks))
+	for idx := range tasks {
+		ancestors[idx] = ancestorsOf(deps, idx)
+	}
+
+	for idx, t := range tasks {
+		switch t.Name {
+		case render:
+			found := false
+			for j := range ancestors[idx] {
+				found = found || tasks[j].Name == report
+			}
+			if !found {
+				r = append(r, fmt.Sprintf("第 %d 个任务[%s] 必须在 depends_on 中直接或间接依赖 %s，否则会在报告生成前执行", idx+1, render, report))
+			}
+		case report:
+			final, rendered := true, false
+			for j, u := range tasks {
+				if ancestors[j][idx] {
+					final = final && u.Name != report
+					rendered = rendered || u.Name == render
+				}
+			}
+			if final && !rendered {
+				r = append(r, fmt.Sprintf("第 %d 个任务[%s] 之后必须包含依赖它的 %s 任务以生成最终报告", idx+1, report, render))
+			}
+		}
+	}
+	return
+}
+
+// ancestorsOf 返回 idx 直接或间接依赖的所有任务下标
+func ancestorsOf(deps [][]int, idx int) (r map[int]bool) {
+	r = map[int]bool{}
+	queue := append([]int{}, deps[idx]...)
+	for len(queue) > 0 {
+		j := queue[0]
+		queue = queue[1:]
+		if r[j] {
+			continue
+		}
+		r[j] = true
+		queue = append(queue, deps[j]...)
+	}
+	return
+}
+
+// names 返回所有可用的 Skill 与 SubAgent 名称
+func (this *PlanningAgent) names() (r []string) {
+	for name := range this.skills {
+		r = append(r, name)
+	}
+	for name := range this.subagents {
+		r = append(r, name)
+	}
+	sort.Strings(r)
+	return
+}
@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Mode int
+
+const (
+	ModeDefault Mode = iota // 读写缓存
+	ModeRefresh             // 忽略已有缓存，重新请求后写入
+	ModeOff                 // 不读也不写
+)
+
+// Cache 为按命名空间划分的磁盘缓存，每个条目保存为 dir/<namespace>/<sha256(key)>.json，
+// 超过命名空间 TTL 的条目视为未命中。同一 Cache 可被并发使用
+type Cache struct {
+	dir  string
+	mode Mode
+	ttls map[string]time.Duration
+
+	mu    sync.Mutex
+	stats map[string]*Stats
+}
+
+type Stats struct {
+	Hits    int
+	Misses  int
+	Expired int // 未命中中因过期而失效的次数
+	Writes  int
+}
+
+type entry struct {
+	Key       string          `json:"key"`
+	CreatedAt time.Time       `json:"created_at"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// New 创建缓存，dir 为空或 mode 为 ModeOff 时缓存不生效，但仍然可以安全调用
+func New(dir string, mode Mode) *Cache {
+	return &Cache{
+		dir:   dir,
+		mode:  mode,
+		ttls:  map[string]time.Duration{},
+		stats: map[string]*Stats{},
+	}
+}
+
+// SetTTL 设置命名空间中条目的有效期，0 表示永不过期
+func (this *Cache) SetTTL(namespace string, ttl time.Duration) *Cache {
+	this.ttls[namespace] = ttl
+	return this
+}
+
+func (this *Cache) Enabled() bool {
+	return this != nil && this.dir != "" && this.mode != ModeOff
+}
+
+// Key 将组成缓存键的各部分拼接为一个键，各部分会被规范化以忽略大小写与多余的空白
+func Key(parts ...string) string {
+	normalized := make([]string, 0, len(parts))
+	for _, p := range parts {
+		normalized = append(normalized, strings.ToLower(strings.Join(strings.Fields(p), " ")))
+	}
+	return strings.Join(normalized, "\x00")
+}
+
+// Get 读取 key 对应的缓存并解码到 v，命中时返回 true
+func (this *Cache) Get(namespace string, key string, v interface{}) bool {
+	if !this.Enabled() || this.mode == ModeRefresh {
+		return false
+	}
+
+	b, err := os.ReadFile(this.path(namespace, key))
+	if err != nil {
+		this.count(namespace, func(s *Stats) { s.Misses++ })
+		return false
+	}
+
+	var e entry
+	if err = json.Unmarshal(b, &e); err != nil || e.Key != key || json.Unmarshal(e.Value, v) != nil {
+		this.count(namespace, func(s *Stats) { s.Misses++ })
+		return false
+	}
+	if ttl := this.ttls[namespace]; ttl > 0 && time.Since(e.CreatedAt) > ttl {
+		this.count(namespace, func(s *Stats) { s.Misses++; s.Expired++ })
+		return false
+	}
+
+	this.count(namespace, func(s *Stats) { s.Hits++ })
+	return true
+}
+
+// Put 将 v 以 JSON 写入缓存，先写临时文件再重命名，避免并发读到不完整的内容
+func (this *Cache) Put(namespace string, key string, v interface{}) (err error) {
+	if !this.Enabled() {
+		return
+	}
+
+	var e = entry{Key: key, CreatedAt: time.Now()}
+	if e.Value, err = json.Marshal(v); err != nil {
+		err = fmt.Errorf("failed to marshal cache entry: %w", err)
+		return
+	}
+	var b []byte
+	if b, err = json.Marshal(e); err != nil {
+		err = fmt.Errorf("failed to marshal cache entry: %w", err)
+		return
+	}
+
+	path := this.path(namespace, key)
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		err = fmt.Errorf("failed to create cache dir: %w", err)
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		err = fmt.Errorf("failed to write cache entry: %w", err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		err = fmt.Errorf("failed to write cache entry: %w", err)
+		return
+	}
+	tmp.Close()
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		err = fmt.Errorf("failed to write cache entry: %w", err)
+		return
+	}
+
+	this.count(namespace, func(s *Stats) { s.Writes++ })
+	return
+}
+
+func (this *Cache) path(namespace string, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(this.dir, namespace, hex.EncodeToString(sum[:])+".json")
+}
+
+func (this *Cache) count(namespace string, fn func(s *Stats)) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.stats[namespace] == nil {
+		this.stats[namespace] = &Stats{}
+	}
+	fn(this.stats[namespace])
+}
+
+// String 按命名空间输出命中统计，尚未使用过缓存时返回空字符串
+func (this *Cache) String() string {
+	if !this.Enabled() {
+		return ""
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	namespaces := make([]string, 0, len(this.stats))
+	for ns := range this.stats {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	var b strings.Builder
+	for _, ns := range namespaces {
+		s := this.stats[ns]
+		b.WriteString(fmt.Sprintf("  %s: 命中 %d 次，未命中 %d 次（其中过期 %d 次），写入 %d 次\n", ns, s.Hits, s.Misses, s.Expired, s.Writes))
+	}
+	return b.String()
+}
@@ -1,36 +1,177 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/ant-libs-go/ant-agent/agents"
 )
 
-var COMMANDS = make(map[string]func(ctx *agents.Context) (quit bool))
+var COMMANDS = make(map[string]func(c context.Context, rt *Runtime, args string) (quit bool))
 
 func init() {
-	COMMANDS["\\help"] = func(ctx *agents.Context) bool {
+	COMMANDS["\\help"] = func(c context.Context, rt *Runtime, args string) bool {
 		fmt.Println("\n📚 可用命令:")
-		fmt.Println("  \\help    - 显示此帮助信息")
-		fmt.Println("  \\clear   - 清除对话历史")
-		fmt.Println("  \\podcast - 从上一份报告生成播客脚本")
-		fmt.Println("  \\exit    - 退出聊天会话")
-		fmt.Println("  \\quit    - 退出聊天会话")
+		fmt.Println("  \\help         - 显示此帮助信息")
+		fmt.Println("  \\clear        - 清除对话历史与对话记忆")
+		fmt.Println("  \\podcast      - 从上一份报告生成播客脚本")
+		fmt.Println("  \\save         - 保存当前会话")
+		fmt.Println("  \\load <id>    - 加载已保存的会话，不指定 id 时列出所有会话")
+		fmt.Println("  \\resume       - 继续执行当前会话中未完成的任务")
+		fmt.Println("  \\ask <q>      - 基于本次研究已收集的资料回答追问，资料不足时补充检索")
+		fmt.Println("  \\revise <q>   - 按照修订要求改写当前报告，不重新研究")
+		fmt.Println("  \\versions     - 列出报告的所有版本")
+		fmt.Println("  \\diff [a b]   - 比较报告的两个版本，默认比较当前版本与上一版本")
+		fmt.Println("  \\rollback <n> - 将报告回滚至指定版本")
+		fmt.Println("  \\usage        - 显示当前会话的 token 用量与预估费用")
+		fmt.Println("  \\exit         - 退出聊天会话")
+		fmt.Println("  \\quit         - 退出聊天会话")
 		return false
 	}
 
-	COMMANDS["\\clear"] = func(ctx *agents.Context) bool {
-		ctx.ClearChatHistory()
-		fmt.Println("✨ 对话历史已清除")
+	COMMANDS["\\clear"] = func(c context.Context, rt *Runtime, args string) bool {
+		rt.ctx.ClearChatHistory()
+		fmt.Println("✨ 对话历史与对话记忆已清除")
 		return false
 	}
 
-	COMMANDS["\\exit"] = func(ctx *agents.Context) bool {
+	COMMANDS["\\save"] = func(c context.Context, rt *Runtime, args string) bool {
+		if rt.cfg.SessionDir == "" {
+			fmt.Println("‼️ 未配置会话目录，请通过 --session-dir 指定")
+			return false
+		}
+		if err := agents.SaveSession(rt.cfg.SessionDir, rt.ctx); err != nil {
+			fmt.Printf("‼️ 会话保存失败: %v\n", err)
+			return false
+		}
+		fmt.Printf("💾 会话 %s 已保存\n", rt.ctx.Id)
+		return false
+	}
+
+	COMMANDS["\\load"] = func(c context.Context, rt *Runtime, args string) bool {
+		if args == "" {
+			ids, err := agents.ListSessions(rt.cfg.SessionDir)
+			if err != nil {
+				fmt.Printf("‼️ 会话列表获取失败: %v\n", err)
+				return false
+			}
+			fmt.Println("\n💾 已保存的会话:")
+			for _, id := range ids {
+				fmt.Printf("  %s\n", id)
+			}
+			return false
+		}
+		if err := rt.Load(args); err != nil {
+			fmt.Printf("‼️ 会话加载失败: %v\n", err)
+			return false
+		}
+		finished := 0
+		for _, t := range rt.ctx.Tasks {
+			if t.Finished() {
+				finished++
+			}
+		}
+		fmt.Printf("💾 会话 %s 已加载: %s，进度 %d/%d\n", rt.ctx.Id, rt.ctx.Input, finished, len(rt.ctx.Tasks))
+		return false
+	}
+
+	COMMANDS["\\resume"] = func(c context.Context, rt *Runtime, args string) bool {
+		if err := rt.Resume(c); err != nil {
+			fmt.Printf("‼️ %v\n", err)
+		}
+		return false
+	}
+
+	COMMANDS["\\ask"] = func(c context.Context, rt *Runtime, args string) bool {
+		if args == "" {
+			fmt.Println("‼️ 请输入问题，例如: \\ask 其中提到的增长率是多少")
+			return false
+		}
+		if err := rt.Ask(c, args); err != nil {
+			fmt.Printf("‼️ %v\n", err)
+		}
+		return false
+	}
+
+	COMMANDS["\\revise"] = func(c context.Context, rt *Runtime, args string) bool {
+		if args == "" {
+			fmt.Println("‼️ 请输入修订要求，例如: \\revise 将结论缩短为三句话")
+			return false
+		}
+		if err := rt.Revise(c, args); err != nil {
+			fmt.Printf("‼️ %v\n", err)
+		}
+		return false
+	}
+
+	COMMANDS["\\versions"] = func(c context.Context, rt *Runtime, args string) bool {
+		if len(rt.ctx.Reports) == 0 {
+			fmt.Println("📄 报告尚未修订过")
+			return false
+		}
+		fmt.Println("\n📄 报告版本:")
+		for _, v := range rt.ctx.Reports {
+			mark := " "
+			if v.Version == rt.ctx.CurrentReport {
+				mark = "*"
+			}
+			instruction := v.Instruction
+			if instruction == "" {
+				instruction = "原始报告"
+			}
+			fmt.Printf(" %s v%d %s %s\n", mark, v.Version, v.CreatedAt.Format("15:04:05"), instruction)
+		}
+		return false
+	}
+
+	COMMANDS["\\diff"] = func(c context.Context, rt *Runtime, args string) bool {
+		a, b := rt.ctx.CurrentReport-1, rt.ctx.CurrentReport
+		if fields := strings.Fields(args); len(fields) == 2 {
+			a, _ = strconv.Atoi(strings.TrimPrefix(fields[0], "v"))
+			b, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "v"))
+		} else if len(fields) != 0 {
+			fmt.Println("‼️ 用法: \\diff [a b]，例如: \\diff 1 3")
+			return false
+		}
+		va, vb := rt.ctx.ReportVersionOf(a), rt.ctx.ReportVersionOf(b)
+		if va == nil || vb == nil {
+			fmt.Printf("‼️ 报告版本不存在，当前共有 %d 个版本\n", len(rt.ctx.Reports))
+			return false
+		}
+		fmt.Printf("\n📄 v%d → v%d:\n%s", va.Version, vb.Version, agents.DiffLines(va.Content, vb.Content))
+		return false
+	}
+
+	COMMANDS["\\rollback"] = func(c context.Context, rt *Runtime, args string) bool {
+		version, err := strconv.Atoi(strings.TrimPrefix(args, "v"))
+		if err != nil {
+			fmt.Println("‼️ 请指定版本号，例如: \\rollback 1")
+			return false
+		}
+		if err = rt.Rollback(c, version); err != nil {
+			fmt.Printf("‼️ %v\n", err)
+		}
+		return false
+	}
+
+	COMMANDS["\\usage"] = func(c context.Context, rt *Runtime, args string) bool {
+		rt.PrintUsage()
+		for idx, t := range rt.ctx.Tasks {
+			if t.Usage != nil {
+				fmt.Printf("  %d. [%s] %s\n", idx+1, t.Name, t.Usage.String())
+			}
+		}
+		return false
+	}
+
+	COMMANDS["\\exit"] = func(c context.Context, rt *Runtime, args string) bool {
 		fmt.Println("👋 再见！")
 		return true
 	}
 
-	COMMANDS["\\quit"] = func(ctx *agents.Context) bool {
+	COMMANDS["\\quit"] = func(c context.Context, rt *Runtime, args string) bool {
 		fmt.Println("👋 再见！")
 		return true
 	}
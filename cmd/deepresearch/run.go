@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/ant-agent/agents"
+	"github.com/ant-libs-go/util"
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	ExitSuccess      = 0
+	ExitFailure      = 1
+	ExitPlanFailure  = 2
+	ExitTaskFailure  = 3
+	ExitOutputFailed = 4
+	ExitCanceled     = 130
+)
+
+// RunCommand 非交互式地完成一次研究：规划、执行并输出最终报告，适用于脚本与定时任务
+func RunCommand(cfg *antagent.Config) *cli.Command {
+	var yes bool
+	var out, jsonOut, eventsOut string
+
+	return &cli.Command{
+		Name:      "run",
+		Usage:     "Run a single research query without interaction",
+		ArgsUsage: "<query>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name: "yes", Usage: "Approve the generated plan without asking",
+				Aliases:     []string{"y"},
+				Destination: &yes,
+			},
+			&cli.StringFlag{
+				Name: "out", Usage: "Write the final markdown report to this file (defaults to stdout, progress always goes to stderr)",
+				Aliases:     []string{"o"},
+				Destination: &out,
+			},
+			&cli.StringFlag{
+				Name: "json", Usage: "Write the session including all task outputs as JSON to this file",
+				Destination: &jsonOut,
+			},
+			&cli.StringFlag{
+				Name: "events", Usage: "Write progress events as JSON lines to this file",
+				Destination: &eventsOut,
+			},
+		},
+		Action: func(c context.Context, cmd *cli.Command) (err error) {
+			if cmd.NArg() == 0 {
+				return cli.Exit("‼️ 请指定研究问题", ExitFailure)
+			}
+			// 没有终端时无法打开计划编辑器
+			if !yes && !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+				return cli.Exit("‼️ 标准输入不是终端，无法确认计划，请使用 --yes 自动确认", ExitFailure)
+			}
+
+			rt, err := NewRuntime(cfg, os.Stderr)
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("‼️ %v", err), ExitFailure)
+			}
+			rt.ctx.Input = strings.Join(cmd.Args().Slice(), " ")
+
+			// Ctrl-C 取消本次研究，已完成任务的输出仍会写入报告
+			c, stop := signal.NotifyContext(c, os.Interrupt)
+			defer stop()
+
+			if eventsOut != "" {
+				var f *os.File
+				if f, err = os.Create(eventsOut); err != nil {
+					return cli.Exit(fmt.Sprintf("‼️ 事件文件创建失败: %v", err), ExitOutputFailed)
+				}
+				defer f.Close()
+				rt.ctx.Events = agents.MultiEvents{rt.ctx.Events, agents.NewJSONEvents(f)}
+			}
+
+			agent := rt.NewPlanningAgent()
+			if yes {
+				agent.SetApprover(agents.AutoApprover)
+			}
+
+			var result *agents.Result
+			if result, err = rt.Plan(c, agent); err != nil {
+				if c.Err() != nil {
+					return cli.Exit(fmt.Sprintf("‼️ %v", err), ExitCanceled)
+				}
+				return cli.Exit(fmt.Sprintf("‼️ %v", err), ExitPlanFailure)
+			}
+
+			code := ExitSuccess
+			report := result.Output
+			if len(result.Tasks) > 0 {
+				if err = rt.Execute(c, agent); err != nil {
+					fmt.Fprintf(os.Stderr, "‼️ %v\n", err)
+					code = util.If(c.Err() != nil, ExitCanceled, ExitTaskFailure).(int)
+				}
+				for _, t := range rt.ctx.Tasks {
+					if t.Status == agents.TaskStatusFailed && code == ExitSuccess {
+						code = ExitTaskFailure
+					}
+				}
+				report = rt.Report()
+			}
+
+			if out == "" {
+				fmt.Printf("%s\n", report)
+			} else if err = os.WriteFile(out, []byte(report), 0644); err != nil {
+				return cli.Exit(fmt.Sprintf("‼️ 报告写入失败: %v", err), ExitOutputFailed)
+			}
+
+			if jsonOut != "" {
+				b, _ := json.MarshalIndent(rt.ctx, "", "  ")
+				if err = os.WriteFile(jsonOut, b, 0644); err != nil {
+					return cli.Exit(fmt.Sprintf("‼️ JSON 写入失败: %v", err), ExitOutputFailed)
+				}
+			}
+			rt.PrintCacheStats()
+
+			if code == ExitCanceled {
+				return cli.Exit("‼️ 研究已取消", code)
+			}
+			if code != ExitSuccess {
+				return cli.Exit("‼️ 部分任务执行失败", code)
+			}
+			return
+		},
+	}
+}
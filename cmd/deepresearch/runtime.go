@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/ant-agent/agents"
+	"github.com/ant-libs-go/ant-agent/cache"
+	"github.com/ant-libs-go/ant-agent/fetch"
+	"github.com/ant-libs-go/ant-agent/llm"
+	"github.com/ant-libs-go/ant-agent/mcps"
+	"github.com/ant-libs-go/ant-agent/search"
+	"github.com/ant-libs-go/ant-agent/skills"
+	"github.com/ant-libs-go/util"
+)
+
+type Runtime struct {
+	cfg         *antagent.Config
+	out         io.Writer // 进度与提示信息的输出位置
+	provider    llm.Provider
+	searcher    search.Provider
+	cache       *cache.Cache
+	fetcher     *fetch.Fetcher
+	mcpClient   *mcps.McpClient
+	skillClient *skills.SkillClient
+	events      agents.Events
+	ctx         *agents.Context
+	sessionId   string // 非空时作为规划出的会话 id，serve 模式下与任务 id 一致
+}
+
+// NewRuntime 创建 Runtime，进度与提示信息输出到 out
+func NewRuntime(cfg *antagent.Config, out io.Writer) (r *Runtime, err error) {
+	r = &Runtime{
+		cfg:    cfg,
+		out:    out,
+		events: agents.NewConsoleEvents(out, cfg.Verbose),
+	}
+	r.ctx = &agents.Context{
+		Offset: 0,
+		Tasks:  make([]*agents.Task, 0, 10),
+		Events: r.events,
+	}
+
+	if r.provider, err = llm.NewProvider(cfg); err != nil {
+		err = fmt.Errorf("LLM 初始化失败: %v", err)
+		return
+	}
+
+	mode := cache.ModeDefault
+	util.IfDo(cfg.Refresh, func() { mode = cache.ModeRefresh })
+	util.IfDo(cfg.NoCache, func() { mode = cache.ModeOff })
+	r.cache = cache.New(cfg.CacheDir, mode).
+		SetTTL(search.CacheNamespace, cfg.SearchCacheTTL).
+		SetTTL(fetch.CacheNamespace, cfg.FetchCacheTTL)
+	r.fetcher = fetch.NewFetcher(&fetch.Options{
+		AllowPrivate: cfg.FetchAllowPrivate,
+		Timeout:      cfg.FetchTimeout,
+		MaxBytes:     int64(cfg.FetchMaxBytes),
+		Cache:        r.cache,
+		Limiter:      fetch.NewHostLimiter(cfg.HostDelay),
+	})
+
+	var er error
+	if r.searcher, er = search.NewProvider(cfg, r.cache); er != nil {
+		r.ctx.Warnf(nil, "‼️ 检索服务初始化失败，网络检索将不可用: %v", er)
+	} else {
+		r.ctx.Debugf(nil, "🔍 检索服务: %s", r.searcher.Name())
+	}
+
+	if cfg.ModelTable != "" {
+		if err = llm.LoadModels(cfg.ModelTable); err != nil {
+			err = fmt.Errorf("模型表加载失败: %v", err)
+			return
+		}
+	}
+
+	r.ctx.Debugf(nil, "🧩 尝试初始化 MCP 配置")
+	if r.mcpClient, er = mcps.NewMcpClient("./mcp.json"); er != nil {
+		r.ctx.Warnf(nil, "‼️ MCP 配置加载失败，如有必要请检查: %v", er)
+	} else {
+		r.ctx.Debugf(nil, "👍 MCP 配置初始化成功")
+	}
+
+	r.ctx.Debugf(nil, "🧩 尝试初始化 SKILL 配置")
+	if r.skillClient, er = skills.NewSkillClient(cfg.SkillsDir); er != nil {
+		r.ctx.Warnf(nil, "‼️ SKILL 配置加载失败，如有必要请检查: %v", er)
+	} else {
+		r.ctx.Debugf(nil, "👍 SKILL 配置初始化成功")
+	}
+
+	r.ctx.McpClient = r.mcpClient
+	return
+}
+
+// Fork 创建共享 MCP 与 SKILL 配置、但拥有独立会话的 Runtime
+func (this *Runtime) Fork() (r *Runtime) {
+	r = &Runtime{
+		cfg:         this.cfg,
+		out:         this.out,
+		provider:    this.provider,
+		searcher:    this.searcher,
+		cache:       this.cache,
+		fetcher:     this.fetcher,
+		mcpClient:   this.mcpClient,
+		skillClient: this.skillClient,
+		events:      this.events,
+		ctx: &agents.Context{
+			Offset:    0,
+			Tasks:     make([]*agents.Task, 0, 10),
+			McpClient: this.mcpClient,
+			Events:    this.events,
+		},
+	}
+	return
+}
+
+func (this *Runtime) NewPlanningAgent() *agents.PlanningAgent {
+	return agents.NewPlanningAgent(this.cfg, this.provider,
+		[]agents.Agent{
+			agents.NewSearchSubAgent(this.cfg, this.provider, this.searcher),
+			agents.NewFetchSubAgent(this.cfg, this.fetcher),
+			agents.NewCrawlSubAgent(this.cfg, this.fetcher),
+			agents.NewAnalyzeSubAgent(this.cfg, this.provider),
+			agents.NewReportSubAgent(this.cfg, this.provider),
+			//agents.NewPPTSubAgent(cfg)
+			agents.NewRenderSubAgent(this.cfg),
+		},
+		this.skillClient.GetSkills())
+}
+
+// Research 对 ctx.Input 进行任务规划并执行，c 被取消时中断规划或执行
+func (this *Runtime) Research(c context.Context) (err error) {
+	agent := this.NewPlanningAgent()
+
+	var result *agents.Result
+	if result, err = this.Plan(c, agent); err != nil {
+		return
+	}
+	if len(result.Tasks) == 0 {
+		this.ctx.Infof(nil, "💬 LLM 判定无需进行任务规划，将直接回复")
+		this.ctx.Emit(agents.EventReport, nil, result.Output)
+		this.remember(c, this.ctx.Input, nil, result.Output)
+		return
+	}
+
+	util.IfDo(this.cfg.SessionDir != "", func() {
+		this.ctx.Infof(nil, "💾 会话 %s 将自动保存至 %s", this.ctx.Id, this.cfg.SessionDir)
+	})
+	if err = this.Execute(c, agent); err != nil {
+		return
+	}
+	this.printReport()
+	this.remember(c, this.ctx.Input, this.ctx.Tasks, this.Report())
+	return
+}
+
+// Plan 对 ctx.Input 进行任务规划，规划出的任务写入 ctx，LLM 判定无需规划时 result.Tasks 为空
+func (this *Runtime) Plan(c context.Context, agent *agents.PlanningAgent) (result *agents.Result, err error) {
+	this.ctx.Lock()
+	this.ctx.Usage = agents.NewUsageStats()
+	this.ctx.Unlock()
+
+	if result, err = agent.Execute(c, this.ctx, nil); err != nil {
+		return
+	}
+
+	if len(result.Tasks) == 0 {
+		return
+	}
+
+	this.ctx.Lock()
+	this.ctx.Id = util.If(this.sessionId != "", this.sessionId, agents.NewSessionId()).(string)
+	this.ctx.Tasks = result.Tasks
+	this.ctx.Replans = 0
+	this.ctx.Sources = nil
+	this.ctx.ClearReports()
+	this.ctx.Plans = result.Output
+	this.ctx.Unlock()
+	return
+}
+
+// Report 返回当前版本的 Markdown 报告，没有 ReportSubAgent 时返回最后一个任务的输出
+func (this *Runtime) Report() string {
+	if report := this.ctx.Report(); report != "" {
+		return report
+	}
+	if len(this.ctx.Tasks) == 0 {
+		return ""
+	}
+	return this.ctx.Tasks[len(this.ctx.Tasks)-1].Output
+}
+
+// Resume 继续执行当前会话中尚未完成的任务
+func (this *Runtime) Resume(c context.Context) (err error) {
+	if len(this.ctx.Tasks) == 0 {
+		err = fmt.Errorf("当前会话没有可恢复的任务")
+		return
+	}
+	if n := this.ctx.RetryFailed(this.cfg.AllPrevious); n > 0 {
+		this.ctx.Infof(nil, "🔁 %d 个失败或缺少依赖输出的任务将重新执行", n)
+	}
+	if err = this.Execute(c, this.NewPlanningAgent()); err != nil {
+		return
+	}
+	this.printReport()
+	this.remember(c, this.ctx.Input, this.ctx.Tasks, this.Report())
+	return
+}
+
+// Ask 基于当前会话已收集的资料回答追问，资料不足时补充检索
+func (this *Runtime) Ask(c context.Context, question string) (err error) {
+	if len(this.ctx.Tasks) == 0 {
+		err = fmt.Errorf("当前会话没有可供问答的资料，请先进行研究")
+		return
+	}
+
+	var result *agents.Result
+	task := &agents.Task{Name: "AskAgent", Description: question}
+	if result, err = agents.NewAskAgent(this.cfg, this.provider, this.searcher).Execute(c, this.ctx, task); err != nil {
+		return
+	}
+	this.ctx.Infof(nil, "\n💬 回答:\n%s", result.Output)
+
+	this.save()
+	this.remember(c, question, nil, result.Output)
+	return
+}
+
+// Revise 按照修订要求改写当前报告并重新渲染，修订结果记为新的报告版本
+func (this *Runtime) Revise(c context.Context, instruction string) (err error) {
+	var result *agents.Result
+	task := &agents.Task{Name: "ReviseAgent", Description: instruction}
+	if result, err = agents.NewReviseAgent(this.cfg, this.provider).Execute(c, this.ctx, task); err != nil {
+		return
+	}
+
+	this.ctx.Lock()
+	version := this.ctx.AddReportVersion(instruction, result.Output)
+	this.ctx.Unlock()
+
+	this.renderReport(c)
+	this.ctx.Infof(nil, "📝 报告已更新为版本 %d", version.Version)
+	this.save()
+	return
+}
+
+// Rollback 将当前报告切换为指定版本并重新渲染
+func (this *Runtime) Rollback(c context.Context, version int) (err error) {
+	this.ctx.Lock()
+	err = this.ctx.RollbackReport(version)
+	this.ctx.Unlock()
+	if err != nil {
+		return
+	}
+
+	this.renderReport(c)
+	this.ctx.Infof(nil, "📝 报告已回滚至版本 %d", version)
+	this.save()
+	return
+}
+
+// renderReport 通过 RenderSubAgent 渲染当前版本的报告
+func (this *Runtime) renderReport(c context.Context) {
+	result, err := agents.NewRenderSubAgent(this.cfg).Execute(c, this.ctx, &agents.Task{Name: "RenderSubAgent"})
+	if err != nil {
+		this.ctx.Warnf(nil, "‼️ 报告渲染失败: %v", err)
+		return
+	}
+	this.ctx.Emit(agents.EventReport, nil, result.Output)
+}
+
+// save 在配置了会话目录时保存当前会话
+func (this *Runtime) save() {
+	if this.cfg.SessionDir == "" {
+		return
+	}
+	if err := agents.SaveSession(this.cfg.SessionDir, this.ctx); err != nil {
+		this.ctx.Warnf(nil, "‼️ 会话保存失败: %v", err)
+	}
+}
+
+// Load 加载已保存的会话并替换当前会话
+func (this *Runtime) Load(id string) (err error) {
+	var ctx *agents.Context
+	if ctx, err = agents.LoadSession(this.cfg.SessionDir, id); err != nil {
+		return
+	}
+	ctx.McpClient = this.mcpClient
+	ctx.Events = this.events
+	this.ctx = ctx
+	return
+}
+
+// Execute 执行当前会话中尚未完成的任务
+func (this *Runtime) Execute(c context.Context, agent *agents.PlanningAgent) (err error) {
+	err = agents.NewExecutor(this.cfg, agent).Run(c, this.ctx)
+	return
+}
+
+// remember 将本轮研究记入对话记忆，供后续请求理解上下文
+func (this *Runtime) remember(c context.Context, query string, tasks []*agents.Task, report string) {
+	agents.NewMemorizer(this.cfg, this.provider).Remember(c, this.ctx, query, tasks, report)
+}
+
+// printReport 输出计划中最后一个 RenderSubAgent 渲染的报告，没有渲染结果时输出 Markdown 报告
+func (this *Runtime) printReport() {
+	report := this.Report()
+	for i := len(this.ctx.Tasks) - 1; i >= 0; i-- {
+		if t := this.ctx.Tasks[i]; t.Name == "RenderSubAgent" && t.Status == agents.TaskStatusDone && t.Output != "" {
+			report = t.Output
+			break
+		}
+	}
+	this.ctx.Emit(agents.EventReport, nil, report)
+	this.PrintUsage()
+}
+
+func (this *Runtime) PrintUsage() {
+	if this.ctx.Usage == nil {
+		fmt.Fprintln(this.out, "📊 暂无 token 用量")
+		return
+	}
+	fmt.Fprintf(this.out, "\n📊 Token 用量:\n")
+	fmt.Fprint(this.out, this.ctx.Usage.String())
+	this.PrintCacheStats()
+}
+
+// PrintCacheStats 在 verbose 模式下输出本进程内的缓存命中统计
+func (this *Runtime) PrintCacheStats() {
+	if stats := this.cache.String(); this.cfg.Verbose && stats != "" {
+		fmt.Fprintf(this.out, "\n🗄️ 缓存统计:\n")
+		fmt.Fprint(this.out, stats)
+	}
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/ant-agent/agents"
+	"github.com/urfave/cli/v3"
+)
+
+type JobStatus string
+
+const (
+	JobStatusPlanning JobStatus = "planning"
+	JobStatusApproval JobStatus = "awaiting_approval"
+	JobStatusRunning  JobStatus = "running"
+	JobStatusDone     JobStatus = "done"
+	JobStatusFailed   JobStatus = "failed"
+	JobStatusCanceled JobStatus = "canceled"
+)
+
+// ServeCommand 以 HTTP API 的方式提供研究服务，进度通过 Server-Sent Events 推送
+func ServeCommand(cfg *antagent.Config) *cli.Command {
+	var addr string
+	var retention time.Duration
+
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Serve research jobs over an HTTP API",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name: "addr", Usage: "Address the HTTP server listens on",
+				Value:       ":8080",
+				Destination: &addr,
+			},
+			&cli.DurationFlag{
+				Name: "job-retention", Usage: "How long finished jobs and their events are kept in memory (0 keeps them forever)",
+				Value:       time.Hour,
+				Destination: &retention,
+			},
+		},
+		Action: func(c context.Context, cmd *cli.Command) (err error) {
+			rt, err := NewRuntime(cfg, os.Stdout)
+			if err != nil {
+				return
+			}
+			srv := NewServer(rt, retention)
+			fmt.Printf("🌐 HTTP 服务已启动: %s\n", addr)
+			return http.ListenAndServe(addr, srv.Handler())
+		},
+	}
+}
+
+type Server struct {
+	rt        *Runtime
+	retention time.Duration // 已结束的任务保留的时长，0 表示一直保留
+	mu        sync.Mutex
+	jobs      map[string]*Job
+}
+
+type Job struct {
+	Id        string
+	Query     string
+	Status    JobStatus
+	Error     string
+	Report    string
+	CreatedAt time.Time
+
+	rt         *Runtime
+	cancel     context.CancelFunc
+	mu         sync.Mutex
+	events     []*agents.Event
+	changed    chan struct{} // 有新事件或任务结束时关闭并替换
+	finished   bool
+	finishedAt time.Time
+	approvals  chan *approval
+}
+
+type approval struct {
+	Approved bool   `json:"approved"`
+	Feedback string `json:"feedback"`
+}
+
+func NewServer(rt *Runtime, retention time.Duration) (r *Server) {
+	r = &Server{
+		rt:        rt,
+		retention: retention,
+		jobs:      map[string]*Job{},
+	}
+	return
+}
+
+func (this *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", this.createJob)
+	mux.HandleFunc("GET /jobs", this.listJobs)
+	mux.HandleFunc("GET /jobs/{id}", this.getJob)
+	mux.HandleFunc("GET /jobs/{id}/events", this.streamEvents)
+	mux.HandleFunc("POST /jobs/{id}/approve", this.approveJob)
+	mux.HandleFunc("POST /jobs/{id}/cancel", this.cancelJob)
+	return mux
+}
+
+func (this *Server) createJob(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query       string `json:"query"`
+		AutoApprove bool   `json:"auto_approve"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Query == "" {
+		writeError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	job := &Job{
+		Query:     req.Query,
+		Status:    JobStatusPlanning,
+		CreatedAt: time.Now(),
+		rt:        this.rt.Fork(),
+		changed:   make(chan struct{}),
+		approvals: make(chan *approval),
+	}
+	job.rt.ctx.Input = req.Query
+	job.rt.ctx.Events = agents.MultiEvents{job.rt.ctx.Events, job}
+	c, cancel := context.WithCancel(context.Background())
+	job.cancel = cancel
+
+	// 会话 id 与任务 id 一致，并发创建的任务不会覆盖彼此的检查点
+	job.Id = agents.NewSessionId()
+	job.rt.sessionId = job.Id
+
+	this.mu.Lock()
+	this.evict()
+	this.jobs[job.Id] = job
+	this.mu.Unlock()
+
+	go job.run(c, req.AutoApprove)
+
+	writeJSON(w, http.StatusCreated, job.view(false))
+}
+
+func (this *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	this.mu.Lock()
+	this.evict()
+	jobs := make([]map[string]interface{}, 0, len(this.jobs))
+	for _, job := range this.jobs {
+		jobs = append(jobs, job.view(false))
+	}
+	this.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i]["created_at"].(time.Time).After(jobs[j]["created_at"].(time.Time))
+	})
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (this *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	job := this.job(r.PathValue("id"))
+	if job == nil {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job.view(true))
+}
+
+func (this *Server) approveJob(w http.ResponseWriter, r *http.Request) {
+	job := this.job(r.PathValue("id"))
+	if job == nil {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+
+	req := &approval{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid approval")
+		return
+	}
+	if !req.Approved && req.Feedback == "" {
+		writeError(w, http.StatusBadRequest, "feedback is required when the plan is not approved")
+		return
+	}
+
+	select {
+	case job.approvals <- req:
+		writeJSON(w, http.StatusAccepted, job.view(false))
+	default:
+		writeError(w, http.StatusConflict, "job is not awaiting approval")
+	}
+}
+
+// cancelJob 取消正在规划或执行的任务，已经开始的 LLM 请求与工具调用会被中断
+func (this *Server) cancelJob(w http.ResponseWriter, r *http.Request) {
+	job := this.job(r.PathValue("id"))
+	if job == nil {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+
+	job.mu.Lock()
+	finished := job.finished
+	job.mu.Unlock()
+	if finished {
+		writeError(w, http.StatusConflict, "job is already finished")
+		return
+	}
+
+	job.cancel()
+	writeJSON(w, http.StatusAccepted, job.view(false))
+}
+
+// streamEvents 先回放已产生的事件，再持续推送新事件，任务结束后关闭连接
+func (this *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	job := this.job(r.PathValue("id"))
+	if job == nil {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	offset := 0
+	for {
+		job.mu.Lock()
+		events := job.events[offset:]
+		changed := job.changed
+		finished := job.finished
+		job.mu.Unlock()
+
+		for _, e := range events {
+			b, _ := json.Marshal(e)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b)
+		}
+		offset += len(events)
+		flusher.Flush()
+
+		if finished {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (this *Server) job(id string) *Job {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.evict()
+	return this.jobs[id]
+}
+
+// evict 移除结束时间超过保留时长的任务，调用方需持有 this.mu
+func (this *Server) evict() {
+	if this.retention <= 0 {
+		return
+	}
+	for id, job := range this.jobs {
+		job.mu.Lock()
+		expired := job.finished && time.Since(job.finishedAt) > this.retention
+		job.mu.Unlock()
+		if expired {
+			delete(this.jobs, id)
+		}
+	}
+}
+
+func (this *Job) run(c context.Context, autoApprove bool) {
+	defer this.finish()
+	defer this.cancel()
+
+	agent := this.rt.NewPlanningAgent()
+	agent.SetApprover(this.approve)
+	if autoApprove {
+		agent.SetApprover(agents.AutoApprover)
+	}
+
+	result, err := this.rt.Plan(c, agent)
+	if err != nil {
+		this.fail(c, err)
+		return
+	}
+
+	report := result.Output
+	if len(result.Tasks) > 0 {
+		this.setStatus(JobStatusRunning)
+		if err = this.rt.Execute(c, agent); err != nil {
+			this.fail(c, err)
+			return
+		}
+		report = this.rt.Report()
+	}
+
+	this.mu.Lock()
+	this.Report = report
+	this.mu.Unlock()
+	this.rt.ctx.Emit(agents.EventReport, nil, report)
+	this.setStatus(JobStatusDone)
+}
+
+// approve 等待调用方通过 approve 接口确认规划结果
+func (this *Job) approve(c context.Context, ctx *agents.Context, result *agents.Result) (approved bool, feedback string, err error) {
+	this.setStatus(JobStatusApproval)
+	select {
+	case req := <-this.approvals:
+		this.setStatus(JobStatusPlanning)
+		return req.Approved, req.Feedback, nil
+	case <-c.Done():
+		return false, "", c.Err()
+	}
+}
+
+// Emit 记录事件供 SSE 推送。流式的增量内容不保留，LLM 请求与应答只保留摘要，
+// 完整的提示词与引用资料可能很大，全部保存在内存中会使长期运行的服务内存持续增长
+func (this *Job) Emit(e *agents.Event) {
+	data := e.Data
+	switch e.Type {
+	case agents.EventDelta:
+		return
+	case agents.EventLLMRequest, agents.EventLLMResponse:
+		if v, ok := e.Data.(*agents.LLMEvent); ok {
+			data = summarizeLLM(v)
+		}
+	}
+
+	// 事件数据在发送时序列化，避免后续修改影响已记录的事件，事件本身可能被其他订阅方共享因此需要拷贝
+	cp := *e
+	if b, err := json.Marshal(data); err == nil {
+		cp.Data = json.RawMessage(b)
+	}
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.events = append(this.events, &cp)
+	this.notify()
+}
+
+// llmSummary 为 LLM 请求与应答事件在 SSE 中的摘要
+type llmSummary struct {
+	Agent            string `json:"agent"`
+	Model            string `json:"model,omitempty"`
+	Messages         int    `json:"messages,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+func summarizeLLM(e *agents.LLMEvent) (r *llmSummary) {
+	r = &llmSummary{Agent: e.Agent, Error: e.Error}
+	if e.Request != nil {
+		r.Model, r.Messages = e.Request.Model, len(e.Request.Messages)
+	}
+	if e.Response != nil {
+		r.PromptTokens, r.CompletionTokens = e.Response.Usage.PromptTokens, e.Response.Usage.CompletionTokens
+	}
+	return
+}
+
+func (this *Job) setStatus(status JobStatus) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.Status = status
+}
+
+// fail 记录任务失败的原因，c 已被取消时任务状态为 canceled
+func (this *Job) fail(c context.Context, err error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.Status = JobStatusFailed
+	if c.Err() != nil {
+		this.Status = JobStatusCanceled
+	}
+	this.Error = err.Error()
+}
+
+func (this *Job) finish() {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.finished = true
+	this.finishedAt = time.Now()
+	this.notify()
+}
+
+// notify 唤醒所有等待新事件的订阅方，调用方需持有 this.mu
+func (this *Job) notify() {
+	close(this.changed)
+	this.changed = make(chan struct{})
+}
+
+func (this *Job) view(detail bool) (r map[string]interface{}) {
+	this.mu.Lock()
+	r = map[string]interface{}{
+		"id":         this.Id,
+		"query":      this.Query,
+		"status":     this.Status,
+		"created_at": this.CreatedAt,
+	}
+	if this.Error != "" {
+		r["error"] = this.Error
+	}
+	if detail && this.Report != "" {
+		r["report"] = this.Report
+	}
+	this.mu.Unlock()
+
+	if detail {
+		r["session"] = this.rt.ctx
+	}
+	return
+}
+
+func writeJSON(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
@@ -1,21 +1,67 @@
 package antagent
 
 import (
+	"time"
+
 	"github.com/urfave/cli/v3"
 )
 
 type Config struct {
-	Model        string
-	ApiBase      string
-	ApiKey       string
-	AutoApprove  bool
-	Verbose      bool
-	TavilyApiKey string
-	SkillsDir    string
+	Provider          string
+	Model             string
+	ApiBase           string
+	ApiKey            string
+	AutoApprove       bool
+	Verbose           bool
+	TavilyApiKey      string
+	SearchProviders   string
+	SearxngURL        string
+	SearchBreadth     int
+	CacheDir          string
+	SearchCacheTTL    time.Duration
+	NoCache           bool
+	Refresh           bool
+	FetchTop          int
+	FetchTimeout      time.Duration
+	FetchMaxBytes     int
+	FetchCacheTTL     time.Duration
+	FetchAllowPrivate bool
+	CrawlDepth        int
+	CrawlMaxPages     int
+	HostDelay         time.Duration
+	SkillsDir         string
+	Concurrency       int
+	TaskTimeout       time.Duration
+	RunTimeout        time.Duration
+	LLMMaxRetries     int
+	LLMRetryDelay     time.Duration
+	LLMMaxRetryDelay  time.Duration
+	LLMConcurrency    int
+	ModelTable        string
+	ContextWindow     int
+	OutputReserve     int
+	BudgetStrategy    string
+	StructuredOutput  string
+	PlanMaxRetries    int
+	MaxPlanTasks      int
+	MaxTaskReplans    int
+	MaxRunReplans     int
+	MaxTotalTasks     int
+	MemoryTokens      int
+	AllPrevious       bool
+	SessionDir        string
+	Resume            string
 }
 
 func DefaultCliFlags(config *Config) (r []cli.Flag) {
 	return []cli.Flag{
+		&cli.StringFlag{
+			Name: "provider", Usage: "LLM provider: openai, ollama (falls back to LLM_PROVIDER env var)",
+			Required:    false,
+			Value:       "openai",
+			Sources:     cli.EnvVars("LLM_PROVIDER"),
+			Destination: &config.Provider,
+		},
 		&cli.StringFlag{
 			Name: "model", Usage: "OpenAI-compatible model name (falls back to OPENAI_MODEL env var)",
 			Required:    true,
@@ -24,15 +70,15 @@ func DefaultCliFlags(config *Config) (r []cli.Flag) {
 			Destination: &config.Model,
 		},
 		&cli.StringFlag{
-			Name: "api-base", Usage: "OpenAI-compatible API base URL (falls back to OPENAI_API_BASE env var)",
-			Required:    true,
+			Name: "api-base", Usage: "OpenAI-compatible API base URL, required by the openai provider (falls back to OPENAI_API_BASE env var)",
+			Required:    false,
 			Aliases:     []string{"b"},
 			Sources:     cli.EnvVars("OPENAI_API_BASE"),
 			Destination: &config.ApiBase,
 		},
 		&cli.StringFlag{
-			Name: "api-key", Usage: "OpenAI-compatible API key (falls back to OPENAI_API_KEY env var)",
-			Required:    true,
+			Name: "api-key", Usage: "OpenAI-compatible API key, required by the openai provider (falls back to OPENAI_API_KEY env var)",
+			Required:    false,
 			Aliases:     []string{"k"},
 			Sources:     cli.EnvVars("OPENAI_API_KEY"),
 			Destination: &config.ApiKey,
@@ -54,11 +100,225 @@ func DefaultCliFlags(config *Config) (r []cli.Flag) {
 			Sources:     cli.EnvVars("TAVILY_API_KEY"),
 			Destination: &config.TavilyApiKey,
 		},
+		&cli.StringFlag{
+			Name: "search-providers", Usage: "Comma-separated search providers tried in order until one returns results: tavily, searxng, duckduckgo, wikipedia (default: all configured ones in that order, falls back to SEARCH_PROVIDERS env var)",
+			Required:    false,
+			Sources:     cli.EnvVars("SEARCH_PROVIDERS"),
+			Destination: &config.SearchProviders,
+		},
+		&cli.StringFlag{
+			Name: "searxng-url", Usage: "Base URL of a self-hosted SearXNG instance with the json format enabled (falls back to SEARXNG_URL env var)",
+			Required:    false,
+			Sources:     cli.EnvVars("SEARXNG_URL"),
+			Destination: &config.SearxngURL,
+		},
+		&cli.IntFlag{
+			Name: "search-breadth", Usage: "Number of complementary queries a search task runs in parallel, overridable per task with the breadth parameter",
+			Required:    false,
+			Value:       3,
+			Destination: &config.SearchBreadth,
+		},
+		&cli.StringFlag{
+			Name: "cache-dir", Usage: "Directory of the on-disk cache for search results and fetched pages (empty disables caching, falls back to CACHE_DIR env var)",
+			Required:    false,
+			Value:       "./.cache",
+			Sources:     cli.EnvVars("CACHE_DIR"),
+			Destination: &config.CacheDir,
+		},
+		&cli.DurationFlag{
+			Name: "search-cache-ttl", Usage: "How long cached search results stay valid (0 means forever)",
+			Required:    false,
+			Value:       24 * time.Hour,
+			Destination: &config.SearchCacheTTL,
+		},
+		&cli.BoolFlag{
+			Name: "no-cache", Usage: "Neither read nor write the on-disk cache",
+			Required:    false,
+			Destination: &config.NoCache,
+		},
+		&cli.BoolFlag{
+			Name: "refresh", Usage: "Ignore cached entries and overwrite them with fresh results",
+			Required:    false,
+			Destination: &config.Refresh,
+		},
+		&cli.IntFlag{
+			Name: "fetch-top", Usage: "Number of top search results a fetch task downloads when no urls are given, overridable per task with the top parameter",
+			Required:    false,
+			Value:       3,
+			Destination: &config.FetchTop,
+		},
+		&cli.DurationFlag{
+			Name: "fetch-timeout", Usage: "Maximum duration of downloading a single page",
+			Required:    false,
+			Value:       20 * time.Second,
+			Destination: &config.FetchTimeout,
+		},
+		&cli.IntFlag{
+			Name: "fetch-max-bytes", Usage: "Maximum size in bytes of a downloaded page, larger pages are truncated",
+			Required:    false,
+			Value:       5 << 20,
+			Destination: &config.FetchMaxBytes,
+		},
+		&cli.DurationFlag{
+			Name: "fetch-cache-ttl", Usage: "How long cached pages stay valid (0 means forever)",
+			Required:    false,
+			Value:       7 * 24 * time.Hour,
+			Destination: &config.FetchCacheTTL,
+		},
+		&cli.BoolFlag{
+			Name: "fetch-allow-private", Usage: "Allow fetching and crawling loopback, private and link-local addresses (WARNING: lets queries reach internal services, never enable for an exposed server)",
+			Required:    false,
+			Destination: &config.FetchAllowPrivate,
+		},
+		&cli.IntFlag{
+			Name: "crawl-depth", Usage: "Maximum link depth followed from the seed page when crawling a site, overridable per task with the depth parameter",
+			Required:    false,
+			Value:       2,
+			Destination: &config.CrawlDepth,
+		},
+		&cli.IntFlag{
+			Name: "crawl-max-pages", Usage: "Maximum number of pages crawled per site, overridable per task with the max_pages parameter",
+			Required:    false,
+			Value:       20,
+			Destination: &config.CrawlMaxPages,
+		},
+		&cli.DurationFlag{
+			Name: "host-delay", Usage: "Minimum interval between requests to the same host when fetching or crawling pages (a longer robots.txt Crawl-delay wins)",
+			Required:    false,
+			Value:       time.Second,
+			Destination: &config.HostDelay,
+		},
 		&cli.StringFlag{
 			Name: "skills-dir", Usage: "Skills directory (falls back to SKILLS_DIR env var)",
 			Required:    false,
 			Sources:     cli.EnvVars("SKILLS_DIR"),
 			Destination: &config.SkillsDir,
 		},
+		&cli.IntFlag{
+			Name: "concurrency", Usage: "Maximum number of tasks executed concurrently",
+			Required:    false,
+			Value:       4,
+			Destination: &config.Concurrency,
+		},
+		&cli.DurationFlag{
+			Name: "task-timeout", Usage: "Maximum duration of a single task (0 means unlimited)",
+			Required:    false,
+			Value:       10 * time.Minute,
+			Destination: &config.TaskTimeout,
+		},
+		&cli.DurationFlag{
+			Name: "run-timeout", Usage: "Maximum duration of a whole research run (0 means unlimited)",
+			Required:    false,
+			Value:       0,
+			Destination: &config.RunTimeout,
+		},
+		&cli.IntFlag{
+			Name: "llm-max-retries", Usage: "Maximum number of retries for rate-limited or failed LLM requests",
+			Required:    false,
+			Value:       3,
+			Destination: &config.LLMMaxRetries,
+		},
+		&cli.DurationFlag{
+			Name: "llm-retry-delay", Usage: "Initial backoff delay between LLM retries, doubled on each attempt",
+			Required:    false,
+			Value:       time.Second,
+			Destination: &config.LLMRetryDelay,
+		},
+		&cli.DurationFlag{
+			Name: "llm-max-retry-delay", Usage: "Maximum backoff delay between LLM retries, retrying stops when the server asks to wait longer",
+			Required:    false,
+			Value:       30 * time.Second,
+			Destination: &config.LLMMaxRetryDelay,
+		},
+		&cli.IntFlag{
+			Name: "llm-concurrency", Usage: "Maximum number of concurrent LLM requests (0 means unlimited)",
+			Required:    false,
+			Value:       4,
+			Destination: &config.LLMConcurrency,
+		},
+		&cli.StringFlag{
+			Name: "model-table", Usage: "JSON file with per-model prices per million tokens and context windows, e.g. {\"<model>\": {\"prompt\": 2, \"completion\": 8, \"context_window\": 128000}}",
+			Required:    false,
+			Aliases:     []string{"price-table"},
+			Sources:     cli.EnvVars("MODEL_TABLE", "PRICE_TABLE"),
+			Destination: &config.ModelTable,
+		},
+		&cli.IntFlag{
+			Name: "context-window", Usage: "Context window in tokens for models missing from the model table",
+			Required:    false,
+			Value:       32000,
+			Destination: &config.ContextWindow,
+		},
+		&cli.IntFlag{
+			Name: "output-reserve", Usage: "Tokens reserved for the model response when budgeting prompts",
+			Required:    false,
+			Value:       4096,
+			Destination: &config.OutputReserve,
+		},
+		&cli.StringFlag{
+			Name: "budget-strategy", Usage: "How over-budget references are compressed: truncate or summarize",
+			Required:    false,
+			Value:       "truncate",
+			Destination: &config.BudgetStrategy,
+		},
+		&cli.StringFlag{
+			Name: "structured-output", Usage: "Structured output mode for the planner: json_schema, json_object or off (falls back to off when the model rejects it)",
+			Required:    false,
+			Value:       "json_schema",
+			Destination: &config.StructuredOutput,
+		},
+		&cli.IntFlag{
+			Name: "plan-max-retries", Usage: "Maximum number of re-prompts when the plan cannot be parsed",
+			Required:    false,
+			Value:       2,
+			Destination: &config.PlanMaxRetries,
+		},
+		&cli.IntFlag{
+			Name: "max-plan-tasks", Usage: "Maximum number of tasks allowed in a plan (0 means unlimited)",
+			Required:    false,
+			Value:       15,
+			Destination: &config.MaxPlanTasks,
+		},
+		&cli.IntFlag{
+			Name: "max-task-replans", Usage: "Maximum number of times a task may insert new tasks and re-run itself (0 means unlimited)",
+			Required:    false,
+			Value:       2,
+			Destination: &config.MaxTaskReplans,
+		},
+		&cli.IntFlag{
+			Name: "max-replans", Usage: "Maximum number of dynamic task insertions per research run (0 means unlimited)",
+			Required:    false,
+			Value:       6,
+			Destination: &config.MaxRunReplans,
+		},
+		&cli.IntFlag{
+			Name: "max-total-tasks", Usage: "Maximum number of tasks in a plan including dynamically inserted ones (0 means unlimited)",
+			Required:    false,
+			Value:       40,
+			Destination: &config.MaxTotalTasks,
+		},
+		&cli.IntFlag{
+			Name: "memory-tokens", Usage: "Token budget of the conversation memory passed to follow-up requests, older turns are summarized beyond it (0 disables memory)",
+			Required:    false,
+			Value:       2000,
+			Destination: &config.MemoryTokens,
+		},
+		&cli.BoolFlag{
+			Name: "all-previous", Usage: "Pass outputs of all previous tasks to each task instead of only its dependencies",
+			Required:    false,
+			Destination: &config.AllPrevious,
+		},
+		&cli.StringFlag{
+			Name: "session-dir", Usage: "Directory where research sessions are checkpointed (empty disables checkpointing)",
+			Required:    false,
+			Value:       "./sessions",
+			Sources:     cli.EnvVars("SESSION_DIR"),
+			Destination: &config.SessionDir,
+		},
+		&cli.StringFlag{
+			Name: "resume", Usage: "Resume the research session with the given id",
+			Required:    false,
+			Destination: &config.Resume,
+		},
 	}
 }
@@ -0,0 +1,175 @@
+package fetch
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// 跟随 sitemap 索引时最多读取的子 sitemap 数
+const maxChildSitemaps = 5
+
+// 链接指向这些类型的文件时不抓取
+var binaryRegexp = regexp.MustCompile(`(?i)\.(jpe?g|png|gif|svg|webp|ico|bmp|css|js|json|zip|gz|tgz|tar|rar|7z|exe|dmg|apk|mp3|mp4|avi|mov|webm|woff2?|ttf|eot)$`)
+
+type CrawlOptions struct {
+	MaxDepth int // 从种子页面起跟随链接的最大层数，0 表示只抓取种子页面
+	MaxPages int // 最多抓取的页面数
+	// OnPage 在每个页面抓取完成或失败后被调用，可为空
+	OnPage func(url string, page *Page, err error)
+}
+
+type CrawlResult struct {
+	Pages      []*Page
+	Failed     map[string]error // 抓取失败的网址及原因
+	Disallowed []string         // 被 robots.txt 禁止抓取的网址
+}
+
+// Crawler 从种子网址开始按广度优先抓取同一站点的页面，遵守 robots.txt，
+// 并将 sitemap 中的网址作为第一层链接。请求频率由 Fetcher 的 Limiter 按主机限制
+type Crawler struct {
+	fetcher *Fetcher
+	opts    *CrawlOptions
+}
+
+func NewCrawler(fetcher *Fetcher, opts *CrawlOptions) *Crawler {
+	return &Crawler{fetcher: fetcher, opts: opts}
+}
+
+type crawlItem struct {
+	url   string
+	depth int
+}
+
+// Crawl 抓取 seed 所在站点，c 被取消时返回已抓取的页面与取消原因
+func (this *Crawler) Crawl(c context.Context, seed string) (r *CrawlResult, err error) {
+	var start *url.URL
+	if start, err = url.Parse(strings.TrimSpace(seed)); err != nil || (start.Scheme != "http" && start.Scheme != "https") || start.Host == "" {
+		err = fmt.Errorf("invalid seed url: %s", seed)
+		return
+	}
+	start.Fragment = ""
+	r = &CrawlResult{Failed: map[string]error{}}
+
+	robots := this.robots(c, start)
+	if robots.CrawlDelay > 0 && this.fetcher.opts.Limiter != nil {
+		this.fetcher.opts.Limiter.SetDelay(start.Host, robots.CrawlDelay)
+	}
+
+	queue := []*crawlItem{{url: start.String(), depth: 0}}
+	seen := map[string]bool{start.String(): true}
+	enqueue := func(link string, depth int) {
+		u, err := url.Parse(link)
+		if err != nil || !sameSite(u, start) || binaryRegexp.MatchString(u.Path) {
+			return
+		}
+		u.Fragment = ""
+		if link = u.String(); !seen[link] {
+			seen[link] = true
+			queue = append(queue, &crawlItem{url: link, depth: depth})
+		}
+	}
+	if this.opts.MaxDepth > 0 {
+		for _, link := range this.sitemap(c, start, robots) {
+			enqueue(link, 1)
+		}
+	}
+
+	for len(queue) > 0 && len(r.Pages) < this.opts.MaxPages {
+		if err = c.Err(); err != nil {
+			return
+		}
+		item := queue[0]
+		queue = queue[1:]
+
+		u, _ := url.Parse(item.url)
+		if !robots.Allowed(u.RequestURI()) {
+			r.Disallowed = append(r.Disallowed, item.url)
+			continue
+		}
+
+		page, er := this.fetcher.Fetch(c, item.url)
+		// 被取消时中断的页面不计为失败
+		if er != nil && c.Err() != nil {
+			err = c.Err()
+			return
+		}
+		if this.opts.OnPage != nil {
+			this.opts.OnPage(item.url, page, er)
+		}
+		if er != nil {
+			r.Failed[item.url] = er
+			continue
+		}
+		r.Pages = append(r.Pages, page)
+
+		if item.depth < this.opts.MaxDepth {
+			for _, link := range page.Links {
+				enqueue(link, item.depth+1)
+			}
+		}
+	}
+	return
+}
+
+// robots 读取站点的 robots.txt，不存在或读取失败时不做限制
+func (this *Crawler) robots(c context.Context, site *url.URL) *Robots {
+	resp, err := this.fetcher.download(c, site.Scheme+"://"+site.Host+"/robots.txt")
+	if err != nil || resp.status != http.StatusOK {
+		return &Robots{}
+	}
+	return ParseRobots(decode(resp.body, resp.contentType))
+}
+
+// sitemap 返回 robots.txt 中声明的或默认位置的 sitemap 中与站点相同的网址，最多 MaxPages 个
+func (this *Crawler) sitemap(c context.Context, site *url.URL, robots *Robots) (r []string) {
+	sitemaps := robots.Sitemaps
+	if len(sitemaps) == 0 {
+		sitemaps = []string{site.Scheme + "://" + site.Host + "/sitemap.xml"}
+	}
+
+	children := 0
+	for i := 0; i < len(sitemaps) && len(r) < this.opts.MaxPages; i++ {
+		resp, err := this.fetcher.download(c, sitemaps[i])
+		if err != nil || resp.status != http.StatusOK {
+			continue
+		}
+
+		var doc struct {
+			URLs     []string `xml:"url>loc"`
+			Sitemaps []string `xml:"sitemap>loc"`
+		}
+		if xml.Unmarshal(resp.body, &doc) != nil {
+			continue
+		}
+		for _, loc := range doc.URLs {
+			if len(r) >= this.opts.MaxPages {
+				break
+			}
+			if u, err := url.Parse(strings.TrimSpace(loc)); err == nil && sameSite(u, site) {
+				r = append(r, u.String())
+			}
+		}
+		// sitemap 索引
+		for _, loc := range doc.Sitemaps {
+			if children < maxChildSitemaps {
+				children++
+				sitemaps = append(sitemaps, strings.TrimSpace(loc))
+			}
+		}
+	}
+	return
+}
+
+// sameSite 判断 u 与 site 是否属于同一站点，忽略 www 前缀
+func sameSite(u *url.URL, site *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := func(u *url.URL) string { return strings.TrimPrefix(strings.ToLower(u.Host), "www.") }
+	return host(u) == host(site)
+}
@@ -0,0 +1,123 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestSite 启动一个带 robots.txt 与 sitemap 的本地站点，返回站点与各路径被请求的次数
+func newTestSite(t *testing.T) (*httptest.Server, map[string]int) {
+	hits := map[string]int{}
+	pages := map[string]string{
+		"/":               `<a href="/a">a</a> <a href="/private/secret">secret</a> <a href="/logo.png">logo</a> <a href="https://other.example/">other</a>`,
+		"/a":              `<a href="/a/deep">deep</a>`,
+		"/a/deep":         `<a href="/a/deeper">deeper</a>`,
+		"/a/deeper":       ``,
+		"/from-sitemap":   ``,
+		"/private/secret": ``,
+	}
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow: /private\n\nSitemap: %s/sitemap.xml\n", srv.URL)
+			return
+		case "/sitemap.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprintf(w, `<?xml version="1.0"?><urlset><url><loc>%s/from-sitemap</loc></url><url><loc>https://other.example/x</loc></url></urlset>`, srv.URL)
+			return
+		}
+		links, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<html><head><title>%s</title></head><body><main><p>Content of page %s.</p>%s</main></body></html>`, r.URL.Path, r.URL.Path, links)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, hits
+}
+
+func TestCrawl(t *testing.T) {
+	cases := []struct {
+		name       string
+		depth      int
+		maxPages   int
+		pages      []string
+		disallowed []string
+	}{
+		{name: "seed only", depth: 0, maxPages: 10, pages: []string{"/"}},
+		{name: "depth 1 with sitemap", depth: 1, maxPages: 10, pages: []string{"/", "/from-sitemap", "/a"}, disallowed: []string{"/private/secret"}},
+		{name: "depth 3", depth: 3, maxPages: 10, pages: []string{"/", "/from-sitemap", "/a", "/a/deep", "/a/deeper"}, disallowed: []string{"/private/secret"}},
+		{name: "max pages", depth: 3, maxPages: 2, pages: []string{"/", "/from-sitemap"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, hits := newTestSite(t)
+			fetcher := NewFetcher(&Options{Client: srv.Client()})
+			res, err := NewCrawler(fetcher, &CrawlOptions{MaxDepth: tc.depth, MaxPages: tc.maxPages}).Crawl(context.Background(), srv.URL+"/")
+			if err != nil {
+				t.Fatalf("crawl failed: %v", err)
+			}
+
+			got := []string{}
+			for _, page := range res.Pages {
+				got = append(got, strings.TrimPrefix(page.URL, srv.URL))
+				if !strings.Contains(page.Content, "Content of page") {
+					t.Errorf("page %s has unexpected content %q", page.URL, page.Content)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.pages) {
+				t.Errorf("pages = %v, want %v", got, tc.pages)
+			}
+
+			disallowed := []string{}
+			for _, u := range res.Disallowed {
+				disallowed = append(disallowed, strings.TrimPrefix(u, srv.URL))
+			}
+			if len(tc.disallowed) == 0 {
+				tc.disallowed = []string{}
+			}
+			if !reflect.DeepEqual(disallowed, tc.disallowed) {
+				t.Errorf("disallowed = %v, want %v", disallowed, tc.disallowed)
+			}
+			if hits["/private/secret"] > 0 || hits["/logo.png"] > 0 {
+				t.Errorf("crawler requested disallowed or binary links: %v", hits)
+			}
+			if len(res.Failed) > 0 {
+				t.Errorf("unexpected failures: %v", res.Failed)
+			}
+		})
+	}
+}
+
+func TestCrawlCanceled(t *testing.T) {
+	srv, _ := newTestSite(t)
+	fetcher := NewFetcher(&Options{Client: srv.Client()})
+	opts := &CrawlOptions{MaxDepth: 3, MaxPages: 10}
+
+	c, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts.OnPage = func(url string, page *Page, err error) {
+		if strings.HasSuffix(url, "/a") {
+			cancel()
+		}
+	}
+
+	res, err := NewCrawler(fetcher, opts).Crawl(c, srv.URL+"/")
+	if err == nil {
+		t.Fatal("expected the crawl to stop with an error")
+	}
+	if res == nil || len(res.Pages) != 3 {
+		t.Fatalf("expected the 3 pages fetched before cancellation, got %+v", res)
+	}
+}
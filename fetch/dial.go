@@ -0,0 +1,56 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress 表示目标为回环、内网或链路本地等非公网地址
+var ErrPrivateAddress = errors.New("private address not allowed")
+
+var (
+	// 运营商级 NAT 地址段，部分云厂商的元数据服务位于其中
+	sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+	// 表示“本网络”的地址段，部分系统会将其路由到本机
+	thisNetwork = netip.MustParsePrefix("0.0.0.0/8")
+)
+
+// NewPublicClient 创建只允许连接公网地址的客户端。检查在建立连接前针对解析后的 IP 进行，
+// 重定向与 DNS 解析到内网的域名同样会被拒绝。为避免经由代理绕过检查，该客户端不使用环境变量中的代理
+func NewPublicClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   publicOnly,
+	}).DialContext
+	return &http.Client{Transport: transport}
+}
+
+// publicOnly 作为 net.Dialer 的 Control 函数，拒绝连接非公网地址
+func publicOnly(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	if !IsPublic(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
+
+// IsPublic 判断 ip 是否为公网地址
+func IsPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip) && !thisNetwork.Contains(ip)
+}
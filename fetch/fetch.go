@@ -0,0 +1,202 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ant-libs-go/ant-agent/cache"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// CacheNamespace 为网页内容在缓存中的命名空间
+const CacheNamespace = "fetch"
+
+const userAgent = "Mozilla/5.0 (compatible; ant-deepresearch/1.0)"
+
+// ErrUnsupported 表示内容类型无法提取文本，例如图片或压缩包
+var ErrUnsupported = errors.New("unsupported content type")
+
+// Page 为下载并提取后的网页内容
+type Page struct {
+	URL         string   `json:"url"` // 跟随重定向后的最终地址
+	Title       string   `json:"title"`
+	Author      string   `json:"author,omitempty"`
+	PublishedAt string   `json:"published_at,omitempty"`
+	ContentType string   `json:"content_type"`
+	Content     string   `json:"content"`             // 提取出的正文，HTML 的标题与列表以 Markdown 表示
+	Links       []string `json:"links,omitempty"`     // 页面中可跟随的绝对链接，仅 HTML 页面提供
+	Truncated   bool     `json:"truncated,omitempty"` // 内容超过大小限制被截断
+	Cached      bool     `json:"-"`
+}
+
+type Options struct {
+	Client       *http.Client  // 为空时使用只允许连接公网地址的客户端，见 NewPublicClient
+	AllowPrivate bool          // 未指定 Client 时允许访问回环、内网与链路本地地址
+	Timeout      time.Duration // 单个页面的下载超时，0 表示不限制
+	MaxBytes     int64         // 单个页面下载的最大字节数，超过部分被丢弃，0 表示不限制
+	Cache        *cache.Cache
+	Limiter      *HostLimiter // 为空时不限制请求频率
+}
+
+// Fetcher 下载网页并提取正文，支持 HTML、PDF 与纯文本，同一 Fetcher 可被并发使用
+type Fetcher struct {
+	opts *Options
+}
+
+func NewFetcher(opts *Options) *Fetcher {
+	switch {
+	case opts.Client != nil:
+	case opts.AllowPrivate:
+		opts.Client = &http.Client{}
+	default:
+		opts.Client = NewPublicClient()
+	}
+	return &Fetcher{opts: opts}
+}
+
+// Fetch 下载 rawURL 并提取正文，命中缓存时不发起请求
+func (this *Fetcher) Fetch(c context.Context, rawURL string) (r *Page, err error) {
+	// 网址的路径与查询参数区分大小写，不能使用会规范化大小写与空白的 cache.Key
+	key := rawURL + "\x00" + fmt.Sprint(this.opts.MaxBytes)
+	r = &Page{}
+	if this.opts.Cache.Get(CacheNamespace, key, r) {
+		r.Cached = true
+		return
+	}
+
+	if r, err = this.fetch(c, rawURL); err != nil {
+		return
+	}
+	// 缓存写入失败不影响下载结果
+	_ = this.opts.Cache.Put(CacheNamespace, key, r)
+	return
+}
+
+func (this *Fetcher) fetch(c context.Context, rawURL string) (r *Page, err error) {
+	var resp *response
+	if resp, err = this.download(c, rawURL); err != nil {
+		return
+	}
+	if resp.status != http.StatusOK {
+		err = fmt.Errorf("failed to fetch %s: status %d", rawURL, resp.status)
+		return
+	}
+	b := resp.body
+	r = &Page{URL: resp.url, Truncated: resp.truncated}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.contentType)
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(b))
+	}
+	r.ContentType = mediaType
+
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		err = ExtractHTML(r, decode(b, resp.contentType))
+	case mediaType == "application/pdf":
+		err = ExtractPDF(r, b)
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" || mediaType == "application/xml":
+		r.Content = strings.TrimSpace(decode(b, resp.contentType))
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupported, mediaType)
+	}
+	if err != nil {
+		r = nil
+		return
+	}
+
+	if r.Title == "" {
+		r.Title = r.URL
+	}
+	if strings.TrimSpace(r.Content) == "" {
+		r, err = nil, fmt.Errorf("no readable content in %s", rawURL)
+	}
+	return
+}
+
+type response struct {
+	url         string // 跟随重定向后的最终地址
+	status      int
+	contentType string
+	body        []byte
+	truncated   bool
+}
+
+// download 按频率限制、超时与大小限制下载 rawURL，非 200 的响应同样返回，由调用方决定如何处理
+func (this *Fetcher) download(c context.Context, rawURL string) (r *response, err error) {
+	if err = this.wait(c, rawURL); err != nil {
+		return
+	}
+
+	if this.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		c, cancel = context.WithTimeout(c, this.opts.Timeout)
+		defer cancel()
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(c, "GET", rawURL, nil); err != nil {
+		err = fmt.Errorf("failed to create request: %v", err)
+		return
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/pdf,text/plain;q=0.9,*/*;q=0.5")
+
+	var resp *http.Response
+	if resp, err = this.opts.Client.Do(req); err != nil {
+		err = fmt.Errorf("failed to fetch %s: %v", rawURL, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var body io.Reader = resp.Body
+	if this.opts.MaxBytes > 0 {
+		body = io.LimitReader(resp.Body, this.opts.MaxBytes+1)
+	}
+	r = &response{url: resp.Request.URL.String(), status: resp.StatusCode, contentType: resp.Header.Get("Content-Type")}
+	if r.body, err = io.ReadAll(body); err != nil {
+		r, err = nil, fmt.Errorf("failed to read %s: %v", rawURL, err)
+		return
+	}
+	if this.opts.MaxBytes > 0 && int64(len(r.body)) > this.opts.MaxBytes {
+		r.body, r.truncated = r.body[:this.opts.MaxBytes], true
+	}
+	return
+}
+
+// wait 按主机限制请求频率，网址无法解析时交由请求报错
+func (this *Fetcher) wait(c context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	return this.opts.Limiter.Wait(c, u.Host)
+}
+
+// decode 按 BOM、Content-Type 与 <meta charset> 声明的编码将响应内容转换为 UTF-8 文本。
+// 没有声明时合法的 UTF-8 原样返回，否则按中文网页最常见的 GB18030（兼容 GBK 与 GB2312）解码
+func decode(b []byte, contentType string) string {
+	e, _, certain := charset.DetermineEncoding(b, contentType)
+	// 没有任何声明时 DetermineEncoding 仅根据前 1024 字节猜测为 UTF-8 或 windows-1252，此时改为按全文判断
+	if !certain && (e == encoding.Nop || e == charmap.Windows1252) {
+		if utf8.Valid(b) {
+			return string(b)
+		}
+		e = simplifiedchinese.GB18030
+	}
+	if s, err := e.NewDecoder().Bytes(b); err == nil {
+		return strings.ToValidUTF8(string(s), "")
+	}
+	return strings.ToValidUTF8(string(b), "")
+}
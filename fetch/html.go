@@ -0,0 +1,341 @@
+package fetch
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/ant-libs-go/util"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 标记 <pre> 中的行
+const preMarker = "\x00"
+
+// 正文候选的最少字符数，<article> 或 <main> 中的文字少于该值时改为按文字密度选择正文
+const minMainChars = 200
+
+var (
+	// 不属于正文的元素，连同其内容一起移除
+	skipTags = map[atom.Atom]bool{
+		atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+		atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+		atom.Form: true, atom.Button: true, atom.Select: true, atom.Iframe: true, atom.Svg: true,
+	}
+	// class 或 id 匹配时视为页面模板中的非正文部分
+	boilerplateRegexp = regexp.MustCompile(`(?i)comment|sidebar|share|social|related|advert|banner|cookie|popup|modal|subscribe|newsletter|breadcrumb|promo|footer|navbar|menu|sponsor`)
+	positiveRegexp    = regexp.MustCompile(`(?i)article|content|post|entry|main|body|text|story`)
+	spaceRegexp       = regexp.MustCompile(`[ \t\r\n\f\x{00a0}]+`)
+	blankLinesRegexp  = regexp.MustCompile(`\n{3,}`)
+)
+
+// ExtractHTML 提取页面的标题、作者、发布时间与正文
+func ExtractHTML(page *Page, text string) (err error) {
+	var doc *html.Node
+	if doc, err = html.Parse(strings.NewReader(text)); err != nil {
+		err = fmt.Errorf("failed to parse html: %v", err)
+		return
+	}
+
+	extractMeta(page, doc)
+	page.Content = render(mainNode(doc))
+	page.Links = extractLinks(page.URL, doc)
+	return
+}
+
+// extractLinks 返回页面中的 http(s) 链接，相对地址按页面地址或 <base> 解析，忽略片段与 rel="nofollow"
+func extractLinks(pageURL string, doc *html.Node) (r []string) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return
+	}
+
+	seen := map[string]bool{}
+	walk(doc, func(n *html.Node) bool {
+		switch {
+		case n.DataAtom == atom.Base && attr(n, "href") != "":
+			if u, err := base.Parse(attr(n, "href")); err == nil {
+				base = u
+			}
+		case n.DataAtom == atom.A && attr(n, "href") != "" && !strings.Contains(attr(n, "rel"), "nofollow"):
+			u, err := base.Parse(strings.TrimSpace(attr(n, "href")))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				break
+			}
+			u.Fragment = ""
+			if link := u.String(); !seen[link] {
+				seen[link] = true
+				r = append(r, link)
+			}
+		}
+		return true
+	})
+	return
+}
+
+func extractMeta(page *Page, doc *html.Node) {
+	meta := map[string]string{}
+	var title, h1, timeAttr, relAuthor string
+
+	walk(doc, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Title:
+			if title == "" {
+				title = textOf(n)
+			}
+		case atom.H1:
+			if h1 == "" {
+				h1 = textOf(n)
+			}
+		case atom.Meta:
+			key := strings.ToLower(attr(n, "property") + attr(n, "name") + attr(n, "itemprop"))
+			if v := strings.TrimSpace(attr(n, "content")); key != "" && v != "" && meta[key] == "" {
+				meta[key] = v
+			}
+		case atom.Time:
+			if timeAttr == "" {
+				timeAttr = attr(n, "datetime")
+			}
+		case atom.A:
+			if relAuthor == "" && strings.Contains(attr(n, "rel"), "author") {
+				relAuthor = textOf(n)
+			}
+		}
+		if prop := attr(n, "itemprop"); n.DataAtom != atom.Meta && prop != "" {
+			switch prop {
+			case "author":
+				if meta["author"] == "" {
+					meta["author"] = textOf(n)
+				}
+			case "datePublished":
+				if meta["datepublished"] == "" {
+					meta["datepublished"] = first(attr(n, "datetime"), attr(n, "content"), textOf(n))
+				}
+			}
+		}
+		return true
+	})
+
+	page.Title = first(meta["og:title"], meta["twitter:title"], title, h1)
+	page.Author = first(meta["author"], meta["article:author"], meta["dc.creator"], relAuthor)
+	if strings.HasPrefix(page.Author, "http") {
+		// article:author 常为作者主页地址
+		page.Author = first(relAuthor, meta["author"])
+	}
+	page.PublishedAt = first(meta["article:published_time"], meta["datepublished"], meta["pubdate"], meta["publishdate"],
+		meta["date"], meta["dc.date"], meta["dcterms.created"], meta["og:published_time"], timeAttr)
+}
+
+// mainNode 选择正文所在的节点：优先使用 <article> 与 <main>，否则按段落文字的分布打分
+func mainNode(doc *html.Node) *html.Node {
+	var article, main, body *html.Node
+	walk(doc, func(n *html.Node) bool {
+		switch {
+		case n.DataAtom == atom.Body && body == nil:
+			body = n
+		case n.DataAtom == atom.Article && article == nil:
+			article = n
+		case (n.DataAtom == atom.Main || attr(n, "role") == "main") && main == nil:
+			main = n
+		}
+		return true
+	})
+	for _, n := range []*html.Node{article, main} {
+		if n != nil && len([]rune(render(n))) >= minMainChars {
+			return n
+		}
+	}
+
+	scores := map[*html.Node]float64{}
+	walk(doc, func(n *html.Node) bool {
+		if skipped(n) {
+			return false
+		}
+		if n.DataAtom != atom.P && n.DataAtom != atom.Pre {
+			return true
+		}
+		text := textOf(n)
+		length := len([]rune(text))
+		if length < 25 || n.Parent == nil {
+			return false
+		}
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")) + min(float64(length)/100, 3)
+		scores[n.Parent] += score
+		if n.Parent.Parent != nil {
+			scores[n.Parent.Parent] += score / 2
+		}
+		return false
+	})
+
+	var best *html.Node
+	bestScore := 0.0
+	for n, score := range scores {
+		if positiveRegexp.MatchString(attr(n, "class") + " " + attr(n, "id")) {
+			score += 25
+		}
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	if best != nil {
+		return best
+	}
+	if body != nil {
+		return body
+	}
+	return doc
+}
+
+// render 将节点转换为文本，标题与列表项以 Markdown 表示
+func render(root *html.Node) string {
+	var b strings.Builder
+	// 块级元素前后换行，连续的空行在最后统一合并
+	newline := func(n int) {
+		b.WriteString(strings.Repeat("\n", n))
+	}
+
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n != root && skipped(n) {
+			return
+		}
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(spaceRegexp.ReplaceAllString(n.Data, " "))
+			return
+		case html.ElementNode, html.DocumentNode:
+		default:
+			return
+		}
+
+		switch n.DataAtom {
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			if text := textOf(n); text != "" {
+				newline(2)
+				b.WriteString(strings.Repeat("#", int(n.Data[1]-'0')) + " " + text)
+				newline(2)
+			}
+			return
+		case atom.Pre:
+			// 代码块保留缩进，以 preMarker 标记的行在合并空白时不会被去除行首空格
+			newline(2)
+			for _, line := range strings.Split(strings.Trim(rawText(n), "\n"), "\n") {
+				b.WriteString(preMarker + line + "\n")
+			}
+			newline(1)
+			return
+		case atom.Br:
+			newline(1)
+			return
+		case atom.Li:
+			newline(1)
+			b.WriteString("- ")
+		case atom.Td, atom.Th:
+			b.WriteString(" | ")
+		case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Blockquote, atom.Ul, atom.Ol,
+			atom.Table, atom.Tr, atom.Dl, atom.Dt, atom.Dd, atom.Figure, atom.Figcaption:
+			newline(util.If(n.DataAtom == atom.Tr, 1, 2).(int))
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+
+		switch n.DataAtom {
+		case atom.Tr:
+			b.WriteString(" |")
+		case atom.P, atom.Div, atom.Section, atom.Blockquote, atom.Ul, atom.Ol, atom.Table, atom.Dl, atom.Figure:
+			newline(2)
+		}
+	}
+	visit(root)
+
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, preMarker) {
+			lines[i] = strings.TrimRight(strings.TrimPrefix(line, preMarker), " \t")
+			continue
+		}
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLinesRegexp.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// skipped 判断节点是否为需要移除的非正文元素
+func skipped(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return n.Type == html.CommentNode
+	}
+	if skipTags[n.DataAtom] || hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	return n.DataAtom != atom.Body && boilerplateRegexp.MatchString(attr(n, "class")+" "+attr(n, "id"))
+}
+
+// walk 深度优先遍历节点，fn 返回 false 时不再进入该节点的子节点
+func walk(n *html.Node, fn func(n *html.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+// hasAttr 判断节点是否带有属性 key，用于 hidden 等值可以为空的布尔属性
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// textOf 返回节点中的可见文字，空白被合并为单个空格
+func textOf(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c != n && skipped(c) {
+			return false
+		}
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data + " ")
+		}
+		return true
+	})
+	return strings.TrimSpace(spaceRegexp.ReplaceAllString(b.String(), " "))
+}
+
+// rawText 返回节点中的文字并保留原有的空白，用于 <pre>
+func rawText(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+		return true
+	})
+	return b.String()
+}
+
+// first 返回第一个非空的值
+func first(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package fetch
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostLimiter 保证对同一主机的相邻两次请求至少间隔指定时间，可按主机单独调整间隔（例如 robots.txt 的 Crawl-delay）
+type HostLimiter struct {
+	mu     sync.Mutex
+	delay  time.Duration
+	delays map[string]time.Duration
+	next   map[string]time.Time
+}
+
+func NewHostLimiter(delay time.Duration) *HostLimiter {
+	return &HostLimiter{
+		delay:  delay,
+		delays: map[string]time.Duration{},
+		next:   map[string]time.Time{},
+	}
+}
+
+// SetDelay 设置 host 的请求间隔，小于默认间隔时不生效
+func (this *HostLimiter) SetDelay(host string, delay time.Duration) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.delays[strings.ToLower(host)] = delay
+}
+
+// Wait 等待直到可以向 host 发起请求，c 被取消时返回错误
+func (this *HostLimiter) Wait(c context.Context, host string) (err error) {
+	if this == nil {
+		return
+	}
+
+	host = strings.ToLower(host)
+	this.mu.Lock()
+	now := time.Now()
+	at := this.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	// 预约下一个时间片，并发的请求依次排队
+	this.next[host] = at.Add(max(this.delay, this.delays[host]))
+	this.mu.Unlock()
+
+	if wait := time.Until(at); wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-c.Done():
+			err = c.Err()
+		case <-t.C:
+		}
+	}
+	return
+}
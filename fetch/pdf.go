@@ -0,0 +1,352 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	// TJ 数组中小于该值的字距调整视为单词间的空格
+	pdfWordSpacing = -200
+	// 单个文档解压后内容流的总字节数上限，防止少量压缩数据膨胀为巨量内容
+	pdfMaxInflatedBytes = 32 << 20
+	// 提取出的文本中不可打印字符的最大占比，超过时视为无法解码
+	pdfMaxUnprintable = 0.2
+)
+
+var (
+	pdfStreamRegexp = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+	pdfInfoRegexp   = regexp.MustCompile(`/(Title|Author|CreationDate)\s*(\((?:\\.|[^\\)])*\)|<[0-9A-Fa-f\s]*>)`)
+	pdfDateRegexp   = regexp.MustCompile(`^D:(\d{4})(\d{2})?(\d{2})?`)
+)
+
+// ExtractPDF 提取 PDF 中的文本与文档信息。只做基础的提取：支持未压缩与 FlateDecode 压缩的内容流，
+// 字符串按 WinAnsiEncoding 或 UTF-16 解码，不解析字体的 ToUnicode 映射。使用 CID 字体（多数中文 PDF）
+// 的文档解码结果大多为不可打印字符，此时返回 ErrUnsupported；扫描件与加密文档同样无法提取
+func ExtractPDF(page *Page, b []byte) (err error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(b), []byte("%PDF")) {
+		err = errors.New("not a pdf document")
+		return
+	}
+
+	for _, m := range pdfInfoRegexp.FindAllSubmatch(b, -1) {
+		value := strings.TrimSpace(strings.ReplaceAll(pdfString(m[2]), string(utf8.RuneError), ""))
+		switch string(m[1]) {
+		case "Title":
+			page.Title = first(page.Title, value)
+		case "Author":
+			page.Author = first(page.Author, value)
+		case "CreationDate":
+			if d := pdfDateRegexp.FindStringSubmatch(value); d != nil && page.PublishedAt == "" {
+				page.PublishedAt = strings.Trim(d[1]+"-"+d[2]+"-"+d[3], "-")
+			}
+		}
+	}
+
+	var text strings.Builder
+	budget := int64(pdfMaxInflatedBytes)
+	for _, loc := range pdfStreamRegexp.FindAllSubmatchIndex(b, -1) {
+		dict := b[loc[2]:loc[3]]
+		start := loc[1]
+		end := bytes.Index(b[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		data := b[start : start+end]
+
+		// 跳过图片与字体等非内容流
+		if bytes.Contains(dict, []byte("/Image")) || bytes.Contains(dict, []byte("/FontFile")) || bytes.Contains(dict, []byte("/Length1")) {
+			continue
+		}
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			if budget <= 0 {
+				break
+			}
+			r, er := zlib.NewReader(bytes.NewReader(data))
+			if er != nil {
+				continue
+			}
+			// 截断的压缩流仍然保留已解压的部分
+			data, _ = io.ReadAll(io.LimitReader(r, budget))
+			budget -= int64(len(data))
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+		if s := pdfText(data); s != "" {
+			text.WriteString(s + "\n\n")
+		}
+	}
+
+	content := strings.TrimSpace(blankLinesRegexp.ReplaceAllString(text.String(), "\n\n"))
+	if unprintable(content) > pdfMaxUnprintable {
+		err = fmt.Errorf("%w: pdf text cannot be decoded without ToUnicode mappings", ErrUnsupported)
+		return
+	}
+	page.Content = strings.ReplaceAll(content, string(utf8.RuneError), "")
+	return
+}
+
+// unprintable 返回 s 中非空白字符里不可打印字符（包括无法解码的字节）的占比
+func unprintable(s string) float64 {
+	total, bad := 0, 0
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if r == utf8.RuneError || !unicode.IsPrint(r) {
+			bad++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(bad) / float64(total)
+}
+
+// pdfText 解析内容流中 BT 与 ET 之间的文本操作符
+func pdfText(data []byte) string {
+	var b strings.Builder
+	operands := [][]byte{}
+	inText := false
+
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			j := pdfLiteralEnd(data, i)
+			operands = append(operands, data[i:j])
+			i = j
+		case c == '<' && i+1 < len(data) && data[i+1] != '<':
+			j := bytes.IndexByte(data[i:], '>')
+			if j < 0 {
+				return b.String()
+			}
+			operands = append(operands, data[i:i+j+1])
+			i += j + 1
+		case c == '[':
+			j := pdfArrayEnd(data, i)
+			operands = append(operands, data[i:j])
+			i = j
+		case isPDFSpace(c) || c == ']' || c == '>' || c == '<' || c == '{' || c == '}' || c == ')':
+			i++
+		default:
+			j := i + 1
+			for j < len(data) && !isPDFSpace(data[j]) && !bytes.ContainsRune([]byte("()<>[]{}/%"), rune(data[j])) {
+				j++
+			}
+			if c == '/' {
+				// 名称对象作为操作数
+				operands = append(operands, data[i:j])
+				i = j
+				continue
+			}
+			token := string(data[i:j])
+			i = j
+			if (token[0] >= '0' && token[0] <= '9') || token[0] == '-' || token[0] == '+' || token[0] == '.' {
+				operands = append(operands, []byte(token))
+				continue
+			}
+
+			switch token {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+				b.WriteString("\n")
+			case "Tj":
+				if inText && len(operands) > 0 {
+					b.WriteString(pdfString(operands[len(operands)-1]))
+				}
+			case "'", "\"":
+				if inText && len(operands) > 0 {
+					b.WriteString("\n" + pdfString(operands[len(operands)-1]))
+				}
+			case "TJ":
+				if inText && len(operands) > 0 {
+					b.WriteString(pdfArray(operands[len(operands)-1]))
+				}
+			case "T*":
+				b.WriteString("\n")
+			case "Td", "TD":
+				// 纵向移动时换行，横向移动时视为空格
+				if len(operands) >= 2 {
+					if y, _ := strconv.ParseFloat(string(operands[len(operands)-1]), 64); y != 0 {
+						b.WriteString("\n")
+					} else {
+						b.WriteString(" ")
+					}
+				}
+			case "Tm":
+				b.WriteString("\n")
+			}
+			operands = operands[:0]
+		}
+	}
+
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spaceRegexp.ReplaceAllString(line, " "))
+	}
+	return strings.TrimSpace(blankLinesRegexp.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+// pdfLiteralEnd 返回从 i 开始的字面量字符串的结束位置，支持嵌套的括号与转义
+func pdfLiteralEnd(data []byte, i int) int {
+	depth := 0
+	for j := i; j < len(data); j++ {
+		switch data[j] {
+		case '\\':
+			j++
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(data)
+}
+
+func pdfArrayEnd(data []byte, i int) int {
+	for j := i + 1; j < len(data); j++ {
+		switch data[j] {
+		case '(':
+			j = pdfLiteralEnd(data, j) - 1
+		case ']':
+			return j + 1
+		}
+	}
+	return len(data)
+}
+
+// pdfArray 拼接 TJ 数组中的字符串，较大的字距调整转换为空格
+func pdfArray(data []byte) string {
+	var b strings.Builder
+	data = bytes.TrimSuffix(bytes.TrimPrefix(data, []byte("[")), []byte("]"))
+	for i := 0; i < len(data); {
+		switch c := data[i]; {
+		case c == '(':
+			j := pdfLiteralEnd(data, i)
+			b.WriteString(pdfString(data[i:j]))
+			i = j
+		case c == '<':
+			j := bytes.IndexByte(data[i:], '>')
+			if j < 0 {
+				return b.String()
+			}
+			b.WriteString(pdfString(data[i : i+j+1]))
+			i += j + 1
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(data) && (data[j] == '.' || (data[j] >= '0' && data[j] <= '9')) {
+				j++
+			}
+			if n, _ := strconv.ParseFloat(string(data[i:j]), 64); n < pdfWordSpacing {
+				b.WriteString(" ")
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	return b.String()
+}
+
+// pdfString 解码字面量字符串 (...) 或十六进制字符串 <...>
+func pdfString(data []byte) string {
+	var raw []byte
+	switch {
+	case bytes.HasPrefix(data, []byte("<")):
+		hex := bytes.Map(func(r rune) rune {
+			if isPDFSpace(byte(r)) || r == '<' || r == '>' {
+				return -1
+			}
+			return r
+		}, data)
+		if len(hex)%2 == 1 {
+			hex = append(hex, '0')
+		}
+		for i := 0; i+1 < len(hex); i += 2 {
+			v, _ := strconv.ParseUint(string(hex[i:i+2]), 16, 8)
+			raw = append(raw, byte(v))
+		}
+	case bytes.HasPrefix(data, []byte("(")):
+		data = bytes.TrimSuffix(data[1:], []byte(")"))
+		for i := 0; i < len(data); i++ {
+			if data[i] != '\\' || i+1 == len(data) {
+				raw = append(raw, data[i])
+				continue
+			}
+			i++
+			switch c := data[i]; c {
+			case 'n':
+				raw = append(raw, '\n')
+			case 'r':
+				raw = append(raw, '\r')
+			case 't':
+				raw = append(raw, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// 行尾的反斜杠表示字符串跨行
+			default:
+				if c >= '0' && c <= '7' {
+					j := i
+					for j < len(data) && j < i+3 && data[j] >= '0' && data[j] <= '7' {
+						j++
+					}
+					v, _ := strconv.ParseUint(string(data[i:j]), 8, 8)
+					raw = append(raw, byte(v))
+					i = j - 1
+				} else {
+					raw = append(raw, c)
+				}
+			}
+		}
+	default:
+		return ""
+	}
+
+	// UTF-16BE 以 BOM 开头，其余按简单字体最常用的 WinAnsiEncoding 解码，控制字符记为 RuneError，
+	// 它们通常来自按字形编号编码的 CID 字体
+	if len(raw) >= 2 && raw[0] == 0xfe && raw[1] == 0xff {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, 0, len(raw))
+	for _, c := range raw {
+		switch {
+		case c == '\n' || c == '\t':
+			runes = append(runes, rune(c))
+		case c == '\r':
+		case c < 0x20 || c == 0x7f:
+			runes = append(runes, utf8.RuneError)
+		default:
+			runes = append(runes, charmap.Windows1252.DecodeByte(c))
+		}
+	}
+	return string(runes)
+}
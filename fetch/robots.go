@@ -0,0 +1,132 @@
+package fetch
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Robots 为 robots.txt 中适用于本程序的规则
+type Robots struct {
+	rules      []robotsRule
+	CrawlDelay time.Duration
+	Sitemaps   []string
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsAgent 为匹配 robots.txt 中 User-agent 时使用的名称
+const robotsAgent = "ant-deepresearch"
+
+// ParseRobots 解析 robots.txt，优先使用名称与 robotsAgent 匹配的分组，否则使用 "*" 分组
+func ParseRobots(text string) (r *Robots) {
+	r = &Robots{}
+
+	type group struct {
+		agents []string
+		rules  []robotsRule
+		delay  time.Duration
+	}
+	groups := []*group{}
+	var current *group
+	lastAgent := false
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// 连续的 User-agent 行属于同一分组
+			if current == nil || !lastAgent {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastAgent = true
+			continue
+		case "allow", "disallow":
+			// 空的 Disallow 表示允许全部
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if current != nil {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					current.delay = time.Duration(v * float64(time.Second))
+				}
+			}
+		case "sitemap":
+			r.Sitemaps = append(r.Sitemaps, value)
+		}
+		lastAgent = false
+	}
+
+	var matched, wildcard *group
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			if agent == "*" && wildcard == nil {
+				wildcard = g
+			} else if agent != "*" && strings.Contains(robotsAgent, agent) && matched == nil {
+				matched = g
+			}
+		}
+	}
+	if matched == nil {
+		matched = wildcard
+	}
+	if matched != nil {
+		r.rules, r.CrawlDelay = matched.rules, matched.delay
+	}
+	return
+}
+
+// Allowed 判断 path（含查询参数）是否允许抓取，以最长匹配的规则为准，长度相同时 Allow 优先
+func (this *Robots) Allowed(path string) bool {
+	if this == nil {
+		return true
+	}
+	allowed, longest := true, -1
+	for _, rule := range this.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			allowed, longest = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// robotsMatch 按 robots.txt 的规则匹配路径前缀，支持 * 通配与结尾的 $
+func robotsMatch(pattern string, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for _, part := range parts[1:] {
+		i := strings.Index(path[pos:], part)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(part)
+	}
+	if !anchored {
+		return true
+	}
+	// 以 $ 结尾时最后一段必须匹配到路径末尾
+	last := parts[len(parts)-1]
+	return len(parts) > 1 && strings.HasSuffix(path, last) || pos == len(path)
+}
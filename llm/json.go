@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	thinkRe = regexp.MustCompile(`(?s)<think>.*?</think>`)
+	fenceRe = regexp.MustCompile("(?s)```(?:json|JSON)?\\s*(.*?)```")
+)
+
+// ParseJSON 从 LLM 应答中提取 JSON 并解析到 v，容忍思考过程、代码块、前后说明文字以及常见的格式错误
+func ParseJSON(content string, v interface{}) (err error) {
+	var text string
+	if text, err = ExtractJSON(content); err != nil {
+		return
+	}
+	if err = json.Unmarshal([]byte(text), v); err != nil {
+		err = fmt.Errorf("invalid json: %w", err)
+		return
+	}
+	return
+}
+
+// ExtractJSON 返回 content 中第一个完整的 JSON 对象或数组，并修复多余的逗号、
+// 字符串中未转义的换行以及被截断导致的括号缺失
+func ExtractJSON(content string) (r string, err error) {
+	content = thinkRe.ReplaceAllString(content, "")
+	// 部分模型省略开头的 <think>，或思考过程未结束即被截断
+	if idx := strings.Index(content, "</think>"); idx != -1 {
+		content = content[idx+len("</think>"):]
+	}
+	if idx := strings.Index(content, "<think>"); idx != -1 {
+		content = content[:idx]
+	}
+
+	candidates := []string{}
+	for _, m := range fenceRe.FindAllStringSubmatch(content, -1) {
+		candidates = append(candidates, m[1])
+	}
+	candidates = append(candidates, content)
+
+	for _, candidate := range candidates {
+		if r = repairJSON(candidate); r != "" && json.Valid([]byte(r)) {
+			return
+		}
+	}
+
+	err = fmt.Errorf("no valid json found in response")
+	return
+}
+
+// repairJSON 从第一个 { 或 [ 开始按括号匹配截取 JSON，同时修复常见的格式错误
+func repairJSON(text string) string {
+	start := strings.IndexAny(text, "{[")
+	if start == -1 {
+		return ""
+	}
+
+	var b strings.Builder
+	stack := []byte{}
+	inString, escaped := false, false
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			case c == '\n':
+				b.WriteString(`\n`)
+				continue
+			case c == '\r':
+				continue
+			case c == '\t':
+				b.WriteString(`\t`)
+				continue
+			}
+			b.WriteByte(c)
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			trimTrailingComma(&b)
+			// 括号不匹配时补全遗漏的括号，无法匹配的多余括号直接忽略
+			if strings.IndexByte(string(stack), c) == -1 {
+				continue
+			}
+			for stack[len(stack)-1] != c {
+				b.WriteByte(stack[len(stack)-1])
+				stack = stack[:len(stack)-1]
+			}
+			stack = stack[:len(stack)-1]
+		}
+		b.WriteByte(c)
+		if len(stack) == 0 {
+			return b.String()
+		}
+	}
+
+	// 应答被截断，补全未闭合的字符串与括号
+	if inString {
+		b.WriteByte('"')
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		trimTrailingComma(&b)
+		b.WriteByte(stack[i])
+	}
+	return b.String()
+}
+
+func trimTrailingComma(b *strings.Builder) {
+	s := strings.TrimRight(b.String(), " \t\r\n")
+	if strings.HasSuffix(s, ",") {
+		s = s[:len(s)-1]
+		b.Reset()
+		b.WriteString(s)
+	}
+}
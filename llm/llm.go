@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Request 使用 OpenAI 的消息与工具格式作为各 Provider 的通用格式，
+// 非 OpenAI 兼容的 Provider 需要自行转换
+type Request struct {
+	Model       string                         `json:"model"`
+	Messages    []openai.ChatCompletionMessage `json:"messages"`
+	Tools       []openai.Tool                  `json:"tools,omitempty"`
+	Temperature float32                        `json:"temperature"`
+	// ResponseFormat 要求模型按 JSON Schema 或 JSON 对象输出，不支持的 Provider 可以忽略
+	ResponseFormat *openai.ChatCompletionResponseFormat `json:"response_format,omitempty"`
+}
+
+type Response struct {
+	Message openai.ChatCompletionMessage `json:"message"`
+	Usage   openai.Usage                 `json:"usage"`
+}
+
+type Provider interface {
+	Name() string
+	// Chat 发起一次完整的对话请求
+	Chat(c context.Context, req *Request) (*Response, error)
+	// ChatStream 以流式方式发起对话请求，增量内容通过 onDelta 回调，返回组装后的完整消息
+	ChatStream(c context.Context, req *Request, onDelta func(delta string)) (*Response, error)
+}
+
+type Factory func(cfg *antagent.Config) (Provider, error)
+
+var factories = map[string]Factory{}
+
+// Register 注册一个 Provider 实现，name 对应 Config.Provider
+func Register(name string, factory Factory) {
+	factories[name] = factory
+}
+
+func NewProvider(cfg *antagent.Config) (r Provider, err error) {
+	name := cfg.Provider
+	if name == "" {
+		name = "openai"
+	}
+
+	factory, ok := factories[name]
+	if !ok {
+		err = fmt.Errorf("unknown llm provider: %s", name)
+		return
+	}
+	if r, err = factory(cfg); err != nil {
+		return
+	}
+
+	r = NewRetryProvider(r, &RetryOptions{
+		MaxRetries:  cfg.LLMMaxRetries,
+		BaseDelay:   cfg.LLMRetryDelay,
+		MaxDelay:    cfg.LLMMaxRetryDelay,
+		Concurrency: cfg.LLMConcurrency,
+	})
+	return
+}
+
+// IsBadRequest 判断错误是否为请求参数不被接受，例如模型不支持 response_format
+func IsBadRequest(err error) bool {
+	var llmErr *Error
+	return errors.As(err, &llmErr) && llmErr.StatusCode == http.StatusBadRequest
+}
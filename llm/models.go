@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"unicode"
+)
+
+// ModelSpec 为模型的价格（每百万 token）与上下文窗口大小
+type ModelSpec struct {
+	Prompt        float64 `json:"prompt"`
+	Completion    float64 `json:"completion"`
+	ContextWindow int     `json:"context_window,omitempty"`
+}
+
+var (
+	modelsMu sync.RWMutex
+	models   = map[string]*ModelSpec{}
+)
+
+// LoadModels 从 JSON 文件加载模型表，格式为 {"<model>": {"prompt": 2, "completion": 8, "context_window": 128000}}
+func LoadModels(path string) (err error) {
+	var b []byte
+	if b, err = os.ReadFile(path); err != nil {
+		err = fmt.Errorf("failed to read model table: %w", err)
+		return
+	}
+
+	table := map[string]*ModelSpec{}
+	if err = json.Unmarshal(b, &table); err != nil {
+		err = fmt.Errorf("failed to parse model table: %w", err)
+		return
+	}
+
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	for model, spec := range table {
+		models[model] = spec
+	}
+	return
+}
+
+func getModel(model string) *ModelSpec {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+	return models[model]
+}
+
+// Cost 根据模型表估算费用，未配置价格的模型返回 0
+func Cost(model string, promptTokens int, completionTokens int) float64 {
+	spec := getModel(model)
+	if spec == nil {
+		return 0
+	}
+	return (float64(promptTokens)*spec.Prompt + float64(completionTokens)*spec.Completion) / 1e6
+}
+
+// ContextWindow 返回模型的上下文窗口大小，模型表中未配置时返回 def
+func ContextWindow(model string, def int) int {
+	if spec := getModel(model); spec != nil && spec.ContextWindow > 0 {
+		return spec.ContextWindow
+	}
+	return def
+}
+
+// EstimateTokens 粗略估算文本的 token 数：CJK 字符按 1 个 token 计，其余按 4 个字符 1 个 token 计
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
@@ -0,0 +1,180 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const OllamaDefaultApiBase = "http://localhost:11434/v1"
+
+func init() {
+	Register("openai", func(cfg *antagent.Config) (Provider, error) {
+		switch {
+		case cfg.ApiBase == "":
+			return nil, errors.New("openai provider requires --api-base or OPENAI_API_BASE")
+		case cfg.ApiKey == "":
+			return nil, errors.New("openai provider requires --api-key or OPENAI_API_KEY")
+		}
+		return NewOpenAIProvider("openai", cfg.ApiKey, cfg.ApiBase), nil
+	})
+	// Ollama 提供 OpenAI 兼容的接口，默认使用本地服务且不需要 API key
+	Register("ollama", func(cfg *antagent.Config) (Provider, error) {
+		base := cfg.ApiBase
+		if base == "" {
+			base = OllamaDefaultApiBase
+		}
+		return NewOpenAIProvider("ollama", cfg.ApiKey, base), nil
+	})
+}
+
+// OpenAIProvider 基于 go-openai 实现，适用于所有 OpenAI 兼容的接口
+type OpenAIProvider struct {
+	name string
+	cli  *openai.Client
+}
+
+func NewOpenAIProvider(name string, apiKey string, apiBase string) (r *OpenAIProvider) {
+	r = &OpenAIProvider{
+		name: name,
+	}
+	openaiCfg := openai.DefaultConfig(apiKey)
+	openaiCfg.BaseURL = apiBase
+	openaiCfg.HTTPClient = &http.Client{Transport: &retryAfterTransport{http.DefaultTransport}}
+	r.cli = openai.NewClientWithConfig(openaiCfg)
+	return
+}
+
+func (this *OpenAIProvider) Name() string {
+	return this.name
+}
+
+func (this *OpenAIProvider) request(req *Request) openai.ChatCompletionRequest {
+	return openai.ChatCompletionRequest{
+		Model:          req.Model,
+		Messages:       req.Messages,
+		Tools:          req.Tools,
+		Temperature:    req.Temperature,
+		ResponseFormat: req.ResponseFormat,
+	}
+}
+
+func (this *OpenAIProvider) Chat(c context.Context, req *Request) (r *Response, err error) {
+	var retryAfter time.Duration
+	c = context.WithValue(c, retryAfterKey{}, &retryAfter)
+
+	var resp openai.ChatCompletionResponse
+	if resp, err = this.cli.CreateChatCompletion(c, this.request(req)); err != nil {
+		err = this.wrap(err, retryAfter)
+		return
+	}
+	if len(resp.Choices) == 0 {
+		err = fmt.Errorf("empty choices in response")
+		return
+	}
+
+	r = &Response{
+		Message: resp.Choices[0].Message,
+		Usage:   resp.Usage,
+	}
+	return
+}
+
+func (this *OpenAIProvider) ChatStream(c context.Context, req *Request, onDelta func(delta string)) (r *Response, err error) {
+	var retryAfter time.Duration
+	c = context.WithValue(c, retryAfterKey{}, &retryAfter)
+
+	var stream *openai.ChatCompletionStream
+	streamReq := this.request(req)
+	streamReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	if stream, err = this.cli.CreateChatCompletionStream(c, streamReq); err != nil {
+		err = this.wrap(err, retryAfter)
+		return
+	}
+	defer stream.Close()
+
+	r = &Response{}
+	r.Message.Role = openai.ChatMessageRoleAssistant
+	for {
+		var resp openai.ChatCompletionStreamResponse
+		if resp, err = stream.Recv(); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			} else {
+				err = this.wrap(err, retryAfter)
+			}
+			return
+		}
+		if resp.Usage != nil {
+			r.Usage = *resp.Usage
+		}
+		if len(resp.Choices) == 0 {
+			continue
+		}
+
+		delta := resp.Choices[0].Delta
+		if delta.Content != "" {
+			r.Message.Content += delta.Content
+			if onDelta != nil {
+				onDelta(delta.Content)
+			}
+		}
+
+		for _, call := range delta.ToolCalls {
+			idx := len(r.Message.ToolCalls)
+			if call.Index != nil {
+				idx = *call.Index
+			}
+			for len(r.Message.ToolCalls) <= idx {
+				r.Message.ToolCalls = append(r.Message.ToolCalls, openai.ToolCall{Type: openai.ToolTypeFunction})
+			}
+			tc := &r.Message.ToolCalls[idx]
+			if call.ID != "" {
+				tc.ID = call.ID
+			}
+			if call.Type != "" {
+				tc.Type = call.Type
+			}
+			tc.Function.Name += call.Function.Name
+			tc.Function.Arguments += call.Function.Arguments
+		}
+	}
+}
+
+// wrap 将 go-openai 的错误转换为 *Error，以便统一判断是否可以重试
+func (this *OpenAIProvider) wrap(err error, retryAfter time.Duration) error {
+	r := &Error{RetryAfter: retryAfter, Err: err}
+
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	if errors.As(err, &apiErr) {
+		r.StatusCode = apiErr.HTTPStatusCode
+	} else if errors.As(err, &reqErr) {
+		r.StatusCode = reqErr.HTTPStatusCode
+	}
+	return r
+}
+
+type retryAfterKey struct{}
+
+// retryAfterTransport 将响应中的 Retry-After 写入请求 context 中的 *time.Duration，
+// go-openai 返回的错误不包含响应头
+type retryAfterTransport struct {
+	http.RoundTripper
+}
+
+func (this *retryAfterTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	if resp, err = this.RoundTripper.RoundTrip(req); err != nil {
+		return
+	}
+	if d, ok := req.Context().Value(retryAfterKey{}).(*time.Duration); ok {
+		*d = ParseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+	return
+}
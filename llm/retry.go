@@ -0,0 +1,212 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Error 为 Provider 返回的统一错误，携带 HTTP 状态码与服务端要求的重试等待时间
+type Error struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (this *Error) Error() string {
+	return this.Err.Error()
+}
+
+func (this *Error) Unwrap() error {
+	return this.Err
+}
+
+// IsRetryable 判断错误是否为限流、服务端异常或网络抖动等可以重试的错误
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		// 额度耗尽同样返回 429，但重试无意义
+		if code, ok := apiErr.Code.(string); ok && code == "insufficient_quota" {
+			return false
+		}
+		return isRetryableStatus(apiErr.HTTPStatusCode)
+	}
+
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) && reqErr.HTTPStatusCode != 0 {
+		return isRetryableStatus(reqErr.HTTPStatusCode)
+	}
+
+	var llmErr *Error
+	if errors.As(err, &llmErr) && llmErr.StatusCode != 0 {
+		return isRetryableStatus(llmErr.StatusCode)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	return false
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+type RetryOptions struct {
+	MaxRetries  int           // 最大重试次数，0 表示不重试
+	BaseDelay   time.Duration // 首次重试的等待时间，之后指数增长
+	MaxDelay    time.Duration // 单次等待时间上限
+	Concurrency int           // 同时进行的 LLM 请求数上限，0 表示不限制
+}
+
+// RetryObserver 在每次重试等待前被调用，attempt 从 1 开始
+type RetryObserver func(attempt int, delay time.Duration, err error)
+
+type retryObserverKey struct{}
+
+// WithRetryObserver 返回携带 observer 的 context，经由该 context 发起的请求在重试时通知 observer
+func WithRetryObserver(c context.Context, observer RetryObserver) context.Context {
+	return context.WithValue(c, retryObserverKey{}, observer)
+}
+
+// RetryProvider 为 Provider 增加并发限制以及带抖动的指数退避重试
+type RetryProvider struct {
+	Provider
+	opts *RetryOptions
+	sem  chan struct{}
+}
+
+func NewRetryProvider(provider Provider, opts *RetryOptions) (r *RetryProvider) {
+	r = &RetryProvider{
+		Provider: provider,
+		opts:     opts,
+	}
+	if opts.Concurrency > 0 {
+		r.sem = make(chan struct{}, opts.Concurrency)
+	}
+	return
+}
+
+func (this *RetryProvider) Chat(c context.Context, req *Request) (r *Response, err error) {
+	err = this.retry(c, func() (er error) {
+		r, er = this.Provider.Chat(c, req)
+		return
+	}, nil)
+	return
+}
+
+func (this *RetryProvider) ChatStream(c context.Context, req *Request, onDelta func(delta string)) (r *Response, err error) {
+	// 已经输出过增量内容时不再重试，避免重复输出
+	streamed := false
+	err = this.retry(c, func() (er error) {
+		r, er = this.Provider.ChatStream(c, req, func(delta string) {
+			streamed = true
+			if onDelta != nil {
+				onDelta(delta)
+			}
+		})
+		return
+	}, func() bool { return !streamed })
+	return
+}
+
+func (this *RetryProvider) retry(c context.Context, fn func() error, retryable func() bool) (err error) {
+	for attempt := 0; ; attempt++ {
+		if err = this.acquire(c); err != nil {
+			return
+		}
+		err = fn()
+		this.release()
+
+		if err == nil || attempt >= this.opts.MaxRetries || !IsRetryable(err) || (retryable != nil && !retryable()) {
+			return
+		}
+
+		delay, ok := this.backoff(attempt, err)
+		if !ok {
+			err = fmt.Errorf("服务端要求 %v 后重试，超出重试等待上限 %v: %w", delay, this.opts.MaxDelay, err)
+			return
+		}
+		if observer, ok := c.Value(retryObserverKey{}).(RetryObserver); ok {
+			observer(attempt+1, delay, err)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-c.Done():
+			err = c.Err()
+			return
+		}
+	}
+}
+
+// backoff 计算第 attempt 次失败后的等待时间，优先使用服务端返回的 Retry-After，
+// Retry-After 超出 MaxDelay 时提前重试也会再次被拒绝，ok 返回 false 表示放弃重试
+func (this *RetryProvider) backoff(attempt int, err error) (delay time.Duration, ok bool) {
+	var llmErr *Error
+	if errors.As(err, &llmErr) && llmErr.RetryAfter > 0 {
+		delay = llmErr.RetryAfter
+		ok = this.opts.MaxDelay <= 0 || delay <= this.opts.MaxDelay
+		return
+	}
+
+	delay, ok = this.opts.BaseDelay<<attempt, true
+	if delay <= 0 || (this.opts.MaxDelay > 0 && delay > this.opts.MaxDelay) {
+		delay = this.opts.MaxDelay
+	}
+	// 加入随机抖动，避免并发请求同时重试
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int63n(half))
+	}
+	return
+}
+
+func (this *RetryProvider) acquire(c context.Context) error {
+	if this.sem == nil {
+		return nil
+	}
+	select {
+	case this.sem <- struct{}{}:
+		return nil
+	case <-c.Done():
+		return c.Err()
+	}
+}
+
+func (this *RetryProvider) release() {
+	if this.sem != nil {
+		<-this.sem
+	}
+}
+
+// ParseRetryAfter 解析 Retry-After 响应头，支持秒数与 HTTP 日期两种格式
+func ParseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
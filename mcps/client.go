@@ -127,9 +127,9 @@ func (this *McpClient) Close() (err error) {
 	return
 }
 
-func (this *McpClient) GetTools() (r []openai.Tool) {
+func (this *McpClient) GetTools(ctx context.Context) (r []openai.Tool) {
 	for name, session := range this.sessions {
-		listToolsResult, err := session.ListTools(context.Background(), &mcp.ListToolsParams{})
+		listToolsResult, err := session.ListTools(ctx, &mcp.ListToolsParams{})
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to list tools from server %s: %v\n", name, err)
 			continue
@@ -0,0 +1,51 @@
+package search
+
+import (
+	"context"
+
+	"github.com/ant-libs-go/ant-agent/cache"
+)
+
+// CacheNamespace 为检索结果在缓存中的命名空间
+const CacheNamespace = "search"
+
+// Optioner 由检索服务实现，返回影响检索结果的选项（例如服务地址、结果数量），参与缓存键的计算
+type Optioner interface {
+	Options() string
+}
+
+// CachedProvider 为检索服务增加磁盘缓存，以服务名称、服务选项与规范化后的查询作为缓存键，
+// 只缓存成功的检索结果
+type CachedProvider struct {
+	provider Provider
+	cache    *cache.Cache
+}
+
+func NewCachedProvider(provider Provider, c *cache.Cache) *CachedProvider {
+	return &CachedProvider{provider: provider, cache: c}
+}
+
+func (this *CachedProvider) Name() string {
+	return this.provider.Name()
+}
+
+func (this *CachedProvider) Search(c context.Context, query string) (r *Response, err error) {
+	options := ""
+	if o, ok := this.provider.(Optioner); ok {
+		options = o.Options()
+	}
+	key := cache.Key(this.provider.Name(), options, query)
+
+	r = &Response{}
+	if this.cache.Get(CacheNamespace, key, r) {
+		r.Cached = true
+		return
+	}
+
+	if r, err = this.provider.Search(c, query); err != nil {
+		return
+	}
+	// 缓存写入失败不影响检索结果
+	_ = this.cache.Put(CacheNamespace, key, r)
+	return
+}
@@ -0,0 +1,100 @@
+package search
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/ant-libs-go/util"
+)
+
+// 内容相似度达到该阈值时视为重复结果
+const similarityThreshold = 0.8
+
+// Deduper 按 URL 与内容相似度去除重复的检索结果，可在多轮检索之间复用
+type Deduper struct {
+	mu       sync.Mutex
+	urls     map[string]bool
+	contents []map[string]bool
+}
+
+func NewDeduper() *Deduper {
+	return &Deduper{urls: map[string]bool{}}
+}
+
+// Add 返回 results 中此前未出现过的结果，并记录它们
+func (this *Deduper) Add(results []*Result) (r []*Result) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	for _, item := range results {
+		key := NormalizeURL(item.URL)
+		if key != "" && this.urls[key] {
+			continue
+		}
+		grams := bigrams(item.Content)
+		if this.duplicated(grams) {
+			continue
+		}
+
+		if key != "" {
+			this.urls[key] = true
+		}
+		if len(grams) > 0 {
+			this.contents = append(this.contents, grams)
+		}
+		r = append(r, item)
+	}
+	return
+}
+
+func (this *Deduper) duplicated(grams map[string]bool) bool {
+	if len(grams) == 0 {
+		return false
+	}
+	for _, other := range this.contents {
+		if jaccard(grams, other) >= similarityThreshold {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeURL 忽略协议、www 前缀、片段与末尾的斜杠，使同一页面的不同写法得到相同的结果
+func NormalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(raw)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	return host + strings.TrimRight(u.EscapedPath(), "/") + util.If(u.RawQuery != "", "?"+u.RawQuery, "").(string)
+}
+
+// bigrams 返回去除空白与标点后的字符二元组，同时适用于中文与英文
+func bigrams(text string) (r map[string]bool) {
+	runes := []rune{}
+	for _, c := range strings.ToLower(text) {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			runes = append(runes, c)
+		}
+	}
+	r = map[string]bool{}
+	for i := 0; i+1 < len(runes); i++ {
+		r[string(runes[i:i+2])] = true
+	}
+	return
+}
+
+func jaccard(a map[string]bool, b map[string]bool) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	inter := 0
+	for k := range a {
+		if b[k] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
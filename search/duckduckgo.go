@@ -0,0 +1,90 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/util"
+)
+
+const DuckDuckGoApiBase = "https://api.duckduckgo.com"
+
+func init() {
+	Register("duckduckgo", func(cfg *antagent.Config) (Provider, error) {
+		return NewDuckDuckGoProvider(DuckDuckGoApiBase), nil
+	})
+}
+
+// DuckDuckGoProvider 使用 DuckDuckGo 的 Instant Answer 接口，无需 API Key，
+// 但只对百科类查询返回摘要
+type DuckDuckGoProvider struct {
+	apiBase string
+	client  *http.Client
+}
+
+func NewDuckDuckGoProvider(apiBase string) (r *DuckDuckGoProvider) {
+	return &DuckDuckGoProvider{
+		apiBase: apiBase,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (this *DuckDuckGoProvider) Name() string {
+	return "duckduckgo"
+}
+
+func (this *DuckDuckGoProvider) Options() string {
+	return this.apiBase
+}
+
+type duckDuckGoTopic struct {
+	Text   string            `json:"Text"`
+	URL    string            `json:"FirstURL"`
+	Topics []duckDuckGoTopic `json:"Topics"` // 分组的相关主题
+}
+
+func (this *DuckDuckGoProvider) Search(c context.Context, query string) (r *Response, err error) {
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(c, "GET", fmt.Sprintf("%s/?format=json&no_html=1&skip_disambig=1&q=%s", this.apiBase, url.QueryEscape(query)), nil); err != nil {
+		err = fmt.Errorf("failed to create request: %v", err)
+		return
+	}
+
+	var result struct {
+		Heading       string            `json:"Heading"`
+		AbstractText  string            `json:"AbstractText"`
+		AbstractURL   string            `json:"AbstractURL"`
+		RelatedTopics []duckDuckGoTopic `json:"RelatedTopics"`
+	}
+	if err = do(this.client, "DuckDuckGo", req, &result); err != nil {
+		return
+	}
+
+	r = &Response{Provider: this.Name()}
+	if result.AbstractText != "" {
+		r.Results = append(r.Results, &Result{Title: util.If(result.Heading != "", result.Heading, query).(string), URL: result.AbstractURL, Content: result.AbstractText})
+	}
+	// 摘要之外补充相关主题
+	var walk func(topics []duckDuckGoTopic)
+	walk = func(topics []duckDuckGoTopic) {
+		for _, topic := range topics {
+			walk(topic.Topics)
+			if topic.Text == "" || topic.URL == "" {
+				continue
+			}
+			title, _, _ := strings.Cut(topic.Text, " - ")
+			r.Results = append(r.Results, &Result{Title: title, URL: topic.URL, Content: topic.Text})
+		}
+	}
+	walk(result.RelatedTopics)
+
+	if len(r.Results) == 0 {
+		r, err = nil, ErrNoResults
+	}
+	return
+}
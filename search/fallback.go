@@ -0,0 +1,69 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FallbackProvider 依次尝试各检索服务，某个服务出错、被限流或没有结果时使用下一个
+type FallbackProvider struct {
+	providers []Provider
+}
+
+func NewFallbackProvider(providers ...Provider) (r *FallbackProvider) {
+	return &FallbackProvider{providers: providers}
+}
+
+func (this *FallbackProvider) Name() string {
+	names := make([]string, 0, len(this.providers))
+	for _, p := range this.providers {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (this *FallbackProvider) Search(c context.Context, query string) (r *Response, err error) {
+	failures := []error{}
+	for _, p := range this.providers {
+		var resp *Response
+		if resp, err = p.Search(c, query); err == nil {
+			resp.Failures = failures
+			r = resp
+			return
+		}
+		// 检索被取消时不再尝试其余服务
+		if c.Err() != nil {
+			return
+		}
+		failures = append(failures, fmt.Errorf("%s: %w", p.Name(), err))
+	}
+
+	if len(failures) > 0 && allNoResults(failures) {
+		err = ErrNoResults
+		return
+	}
+	err = errors.Join(failures...)
+	return
+}
+
+// Spread 返回第 i 个并行查询使用的检索服务。p 为回退链时从第 i 个服务开始轮转，
+// 使并行的查询分散到不同的检索服务上，某个服务失败时仍会回退到其余服务
+func Spread(p Provider, i int) Provider {
+	f, ok := p.(*FallbackProvider)
+	if !ok || len(f.providers) < 2 {
+		return p
+	}
+	k := i % len(f.providers)
+	return NewFallbackProvider(append(append([]Provider{}, f.providers[k:]...), f.providers[:k]...)...)
+}
+
+func allNoResults(failures []error) bool {
+	for _, f := range failures {
+		if !errors.Is(f, ErrNoResults) {
+			return false
+		}
+	}
+	return true
+}
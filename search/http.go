@@ -0,0 +1,33 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// 错误信息中保留的响应内容长度
+const maxErrorBody = 512
+
+// do 发送请求并将 JSON 响应解码到 v，非 200 响应返回 *Error
+func do(client *http.Client, provider string, req *http.Request, v interface{}) (err error) {
+	var resp *http.Response
+	if resp, err = client.Do(req); err != nil {
+		err = fmt.Errorf("failed to perform %s search: %v", provider, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		err = &Error{Provider: provider, StatusCode: resp.StatusCode, Body: string(body)}
+		return
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		err = fmt.Errorf("failed to decode %s response: %v", provider, err)
+		return
+	}
+	return
+}
@@ -0,0 +1,110 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+	"github.com/ant-libs-go/ant-agent/cache"
+)
+
+// DefaultProviders 为未指定检索服务时依次尝试的顺序，未配置的服务会被跳过
+var DefaultProviders = []string{"tavily", "searxng", "duckduckgo", "wikipedia"}
+
+// ErrNoResults 表示检索服务正常返回但没有任何结果
+var ErrNoResults = errors.New("no results found")
+
+// ErrNotConfigured 表示检索服务缺少必要的配置，例如 API Key 或服务地址
+var ErrNotConfigured = errors.New("not configured")
+
+// Result 为一条检索结果
+type Result struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Content string `json:"content"`
+}
+
+type Response struct {
+	Provider string    `json:"provider"` // 实际返回结果的检索服务
+	Results  []*Result `json:"results"`
+	Images   []string  `json:"images,omitempty"`
+	// Failures 为回退链中在 Provider 之前失败的检索服务及原因
+	Failures []error `json:"-"`
+	Cached   bool    `json:"-"` // 结果是否来自缓存
+}
+
+type Provider interface {
+	Name() string
+	// Search 检索 query，没有结果时返回 ErrNoResults
+	Search(c context.Context, query string) (*Response, error)
+}
+
+// Error 为检索服务返回的非 200 响应，StatusCode 为 429 时表示被限流
+type Error struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (this *Error) Error() string {
+	return fmt.Sprintf("%s API returned status %d: %s", this.Provider, this.StatusCode, this.Body)
+}
+
+type Factory func(cfg *antagent.Config) (Provider, error)
+
+var factories = map[string]Factory{}
+
+// Register 注册一个检索服务实现，name 对应 Config.SearchProviders 中的名称
+func Register(name string, factory Factory) {
+	factories[name] = factory
+}
+
+// NewProvider 按 cfg.SearchProviders 指定的顺序创建检索服务，多个服务时组成回退链。
+// 未指定时按 DefaultProviders 的顺序使用所有已配置的服务。c 不为空时各服务的结果分别缓存
+func NewProvider(cfg *antagent.Config, c *cache.Cache) (r Provider, err error) {
+	names, auto := []string{}, strings.TrimSpace(cfg.SearchProviders) == ""
+	if auto {
+		names = DefaultProviders
+	} else {
+		for _, name := range strings.Split(cfg.SearchProviders, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	providers := []Provider{}
+	for _, name := range names {
+		factory, ok := factories[name]
+		if !ok {
+			err = fmt.Errorf("unknown search provider: %s", name)
+			return
+		}
+		var p Provider
+		if p, err = factory(cfg); err != nil {
+			if auto && errors.Is(err, ErrNotConfigured) {
+				err = nil
+				continue
+			}
+			err = fmt.Errorf("search provider %s: %v", name, err)
+			return
+		}
+		if c.Enabled() {
+			p = NewCachedProvider(p, c)
+		}
+		providers = append(providers, p)
+	}
+	if len(providers) == 0 {
+		err = errors.New("no search provider available")
+		return
+	}
+
+	if len(providers) == 1 {
+		r = providers[0]
+		return
+	}
+	r = NewFallbackProvider(providers...)
+	return
+}
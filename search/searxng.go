@@ -0,0 +1,81 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+)
+
+// SearXNG 单次返回的结果数上限
+const searxngMaxResults = 20
+
+func init() {
+	Register("searxng", func(cfg *antagent.Config) (Provider, error) {
+		if cfg.SearxngURL == "" {
+			return nil, fmt.Errorf("searxng url %w", ErrNotConfigured)
+		}
+		return NewSearxngProvider(cfg.SearxngURL), nil
+	})
+}
+
+// SearxngProvider 使用自建的 SearXNG 实例，实例需要在 search.formats 中启用 json
+type SearxngProvider struct {
+	apiBase string
+	client  *http.Client
+}
+
+func NewSearxngProvider(apiBase string) (r *SearxngProvider) {
+	return &SearxngProvider{
+		apiBase: strings.TrimRight(apiBase, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (this *SearxngProvider) Name() string {
+	return "searxng"
+}
+
+func (this *SearxngProvider) Options() string {
+	return this.apiBase
+}
+
+func (this *SearxngProvider) Search(c context.Context, query string) (r *Response, err error) {
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(c, "GET", fmt.Sprintf("%s/search?format=json&q=%s", this.apiBase, url.QueryEscape(query)), nil); err != nil {
+		err = fmt.Errorf("failed to create request: %v", err)
+		return
+	}
+
+	var result struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+			ImgSrc  string `json:"img_src"`
+		} `json:"results"`
+	}
+	if err = do(this.client, "SearXNG", req, &result); err != nil {
+		return
+	}
+
+	r = &Response{Provider: this.Name()}
+	for _, item := range result.Results {
+		if item.ImgSrc != "" {
+			r.Images = append(r.Images, item.ImgSrc)
+			continue
+		}
+		if len(r.Results) < searxngMaxResults {
+			r.Results = append(r.Results, &Result{Title: item.Title, URL: item.URL, Content: item.Content})
+		}
+	}
+
+	if len(r.Results) == 0 && len(r.Images) == 0 {
+		r, err = nil, ErrNoResults
+	}
+	return
+}
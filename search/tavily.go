@@ -0,0 +1,77 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+)
+
+const TavilyApiBase = "https://api.tavily.com"
+
+func init() {
+	Register("tavily", func(cfg *antagent.Config) (Provider, error) {
+		if cfg.TavilyApiKey == "" {
+			return nil, fmt.Errorf("tavily api key %w", ErrNotConfigured)
+		}
+		return NewTavilyProvider(cfg.TavilyApiKey, TavilyApiBase), nil
+	})
+}
+
+type TavilyProvider struct {
+	apiKey  string
+	apiBase string
+	client  *http.Client
+}
+
+func NewTavilyProvider(apiKey string, apiBase string) (r *TavilyProvider) {
+	return &TavilyProvider{
+		apiKey:  apiKey,
+		apiBase: apiBase,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (this *TavilyProvider) Name() string {
+	return "tavily"
+}
+
+func (this *TavilyProvider) Options() string {
+	return this.apiBase + " depth=basic max_results=20 images"
+}
+
+func (this *TavilyProvider) Search(c context.Context, query string) (r *Response, err error) {
+	b, _ := json.Marshal(map[string]interface{}{
+		"query":          query,
+		"search_depth":   "basic",
+		"max_results":    20,
+		"include_images": true,
+	})
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(c, "POST", this.apiBase+"/search", bytes.NewBuffer(b)); err != nil {
+		err = fmt.Errorf("failed to create request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", this.apiKey))
+
+	var result struct {
+		Results []*Result `json:"results"`
+		Images  []string  `json:"images"`
+	}
+	if err = do(this.client, "Tavily", req, &result); err != nil {
+		return
+	}
+
+	if len(result.Results) == 0 && len(result.Images) == 0 {
+		err = ErrNoResults
+		return
+	}
+	r = &Response{Provider: this.Name(), Results: result.Results, Images: result.Images}
+	return
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	antagent "github.com/ant-libs-go/ant-agent"
+)
+
+const WikipediaApiBase = "https://en.wikipedia.org"
+
+func init() {
+	Register("wikipedia", func(cfg *antagent.Config) (Provider, error) {
+		return NewWikipediaProvider(WikipediaApiBase), nil
+	})
+}
+
+// WikipediaProvider 全文检索维基百科条目并返回条目导言
+type WikipediaProvider struct {
+	apiBase string
+	client  *http.Client
+}
+
+func NewWikipediaProvider(apiBase string) (r *WikipediaProvider) {
+	return &WikipediaProvider{
+		apiBase: apiBase,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (this *WikipediaProvider) Name() string {
+	return "wikipedia"
+}
+
+func (this *WikipediaProvider) Options() string {
+	return this.apiBase + " limit=5"
+}
+
+func (this *WikipediaProvider) Search(c context.Context, query string) (r *Response, err error) {
+	params := url.Values{
+		"action":      {"query"},
+		"format":      {"json"},
+		"generator":   {"search"},
+		"gsrsearch":   {query},
+		"gsrlimit":    {"5"},
+		"prop":        {"extracts"},
+		"exintro":     {""},
+		"explaintext": {""},
+		"exlimit":     {"max"},
+		"redirects":   {"1"},
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(c, "GET", this.apiBase+"/w/api.php?"+params.Encode(), nil); err != nil {
+		err = fmt.Errorf("failed to create request: %v", err)
+		return
+	}
+
+	var result struct {
+		Query struct {
+			Pages map[string]struct {
+				Index   int    `json:"index"`
+				Title   string `json:"title"`
+				Extract string `json:"extract"`
+			} `json:"pages"`
+		} `json:"query"`
+	}
+	if err = do(this.client, "Wikipedia", req, &result); err != nil {
+		return
+	}
+
+	// pages 为无序的 map，按检索相关度排序
+	pages := make([]string, 0, len(result.Query.Pages))
+	for k := range result.Query.Pages {
+		pages = append(pages, k)
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		return result.Query.Pages[pages[i]].Index < result.Query.Pages[pages[j]].Index
+	})
+
+	r = &Response{Provider: this.Name()}
+	for _, k := range pages {
+		page := result.Query.Pages[k]
+		if page.Extract == "" {
+			continue
+		}
+		// 清理一些常见的维基百科 API 伪影
+		extract := strings.TrimSpace(strings.ReplaceAll(page.Extract, "(listen)", ""))
+		r.Results = append(r.Results, &Result{
+			Title:   page.Title,
+			URL:     this.apiBase + "/wiki/" + url.PathEscape(strings.ReplaceAll(page.Title, " ", "_")),
+			Content: extract,
+		})
+	}
+
+	if len(r.Results) == 0 {
+		r, err = nil, ErrNoResults
+	}
+	return
+}